@@ -0,0 +1,143 @@
+package httpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// BatchCodec defines how a batch of calls is combined into a single request and how the batched response is split back into per call responses
+type BatchCodec struct {
+	// Encode combines the specs into the body of the batch request
+	Encode func(specs []RequestSpec) (io.Reader, error)
+
+	// Decode splits the batch response into one response per spec, in the order the specs were added
+	Decode func(resp *http.Response) ([]*http.Response, error)
+}
+
+// Batch accumulates calls and sends them to a batch endpoint as a single POST request
+type Batch struct {
+	client   *Client
+	resource string
+	headers  map[string]string
+	codec    BatchCodec
+
+	mu       sync.Mutex
+	specs    []RequestSpec
+	promises []*Promise
+}
+
+// Promise resolves to the Result of a call once its batch has been flushed
+type Promise struct {
+	done   chan struct{}
+	result Result
+}
+
+// Done returns a channel that is closed when the promise resolves
+func (p *Promise) Done() <-chan struct{} {
+	return p.done
+}
+
+// Result blocks until the promise resolves and returns its Result
+func (p *Promise) Result() Result {
+	<-p.done
+	return p.result
+}
+
+func (p *Promise) resolve(result Result) {
+	p.result = result
+	close(p.done)
+}
+
+// NewBatch creates a Batch that sends its calls to the supplied batch endpoint
+func (c *Client) NewBatch(resource string, headers map[string]string, codec BatchCodec) *Batch {
+	return &Batch{
+		client:   c,
+		resource: resource,
+		headers:  headers,
+		codec:    codec,
+	}
+}
+
+// Add queues a call for the next flush and returns a promise for its result
+func (b *Batch) Add(spec RequestSpec) *Promise {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	promise := &Promise{done: make(chan struct{})}
+
+	b.specs = append(b.specs, spec)
+	b.promises = append(b.promises, promise)
+
+	return promise
+}
+
+// Flush sends the queued calls as a single request and resolves their promises with the demultiplexed responses.
+// When the batch request itself fails, every promise resolves with the returned error
+func (b *Batch) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	specs, promises := b.specs, b.promises
+	b.specs, b.promises = nil, nil
+	b.mu.Unlock()
+
+	if len(specs) == 0 {
+		return nil
+	}
+
+	responses, err := b.send(ctx, specs)
+	if err != nil {
+		for _, promise := range promises {
+			promise.resolve(Result{Err: err})
+		}
+
+		return err
+	}
+
+	for i, promise := range promises {
+		sub := responses[i]
+		if sub.Body == nil {
+			sub.Body = http.NoBody
+		}
+
+		promise.resolve(b.client.newResult(sub, specs[i].Decoded))
+		sub.Body.Close()
+	}
+
+	return nil
+}
+
+// send issues the batch request and splits its response, ensuring there is one response per spec
+func (b *Batch) send(ctx context.Context, specs []RequestSpec) ([]*http.Response, error) {
+	body, err := b.codec.Encode(specs)
+	if err != nil {
+		return nil, &BatchError{"failed to encode batch: " + err.Error()}
+	}
+
+	req, err := b.client.newRequest(ctx, http.MethodPost, b.resource, body, b.headers)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.client.roundTrip(req)
+	if err != nil {
+		return nil, &RequestError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newBadStatusCode(resp, b.client.bodyPool)
+	}
+
+	responses, err := b.codec.Decode(resp)
+	if err != nil {
+		return nil, &BatchError{"failed to decode batch: " + err.Error()}
+	}
+
+	if len(responses) != len(specs) {
+		return nil, &BatchError{"expected " + strconv.Itoa(len(specs)) + " responses, got " + strconv.Itoa(len(responses))}
+	}
+
+	return responses, nil
+}
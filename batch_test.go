@@ -0,0 +1,112 @@
+package httpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var paths []string
+		if err := json.NewDecoder(r.Body).Decode(&paths); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		parts := make([]map[string]interface{}, 0, len(paths))
+		for _, path := range paths {
+			if path == "/missing" {
+				parts = append(parts, map[string]interface{}{"status": http.StatusNotFound, "body": map[string]string{"error": "not found"}})
+				continue
+			}
+
+			parts = append(parts, map[string]interface{}{"status": http.StatusOK, "body": map[string]string{"name": path}})
+		}
+
+		json.NewEncoder(w).Encode(parts)
+	}))
+	defer srv.Close()
+
+	codec := BatchCodec{
+		Encode: func(specs []RequestSpec) (io.Reader, error) {
+			paths := make([]string, 0, len(specs))
+			for _, spec := range specs {
+				paths = append(paths, spec.Resource)
+			}
+
+			data, err := json.Marshal(paths)
+			return bytes.NewReader(data), err
+		},
+		Decode: func(resp *http.Response) ([]*http.Response, error) {
+			var parts []struct {
+				Status int
+				Body   json.RawMessage
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&parts); err != nil {
+				return nil, err
+			}
+
+			responses := make([]*http.Response, 0, len(parts))
+			for _, part := range parts {
+				responses = append(responses, &http.Response{
+					StatusCode: part.Status,
+					Status:     http.StatusText(part.Status),
+					Header:     http.Header{},
+					Body:       io.NopCloser(bytes.NewReader(part.Body)),
+				})
+			}
+
+			return responses, nil
+		},
+	}
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch := client.NewBatch("/batch", nil, codec)
+
+	var first, second struct{ Name string }
+	firstPromise := batch.Add(RequestSpec{Method: http.MethodGet, Resource: "/first", Decoded: &first})
+	secondPromise := batch.Add(RequestSpec{Method: http.MethodGet, Resource: "/second", Decoded: &second})
+	missingPromise := batch.Add(RequestSpec{Method: http.MethodGet, Resource: "/missing"})
+
+	if err := batch.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 1 {
+		t.Fatalf("expected a single batched request, got %d", requests)
+	}
+
+	for _, promise := range []*Promise{firstPromise, secondPromise, missingPromise} {
+		select {
+		case <-promise.Done():
+		default:
+			t.Fatal("expected every promise to be resolved after Flush")
+		}
+	}
+
+	if !firstPromise.Result().IsSuccess() || first.Name != "/first" {
+		t.Fatalf("unexpected first result %+v, decoded %+v", firstPromise.Result(), first)
+	}
+
+	if !secondPromise.Result().IsSuccess() || second.Name != "/second" {
+		t.Fatalf("unexpected second result %+v, decoded %+v", secondPromise.Result(), second)
+	}
+
+	var statusErr *BadStatusCode
+	if result := missingPromise.Result(); result.StatusCode != http.StatusNotFound || !errors.As(result.Err, &statusErr) {
+		t.Fatalf("expected a 404 status error, got %d %v", result.StatusCode, result.Err)
+	}
+}
@@ -0,0 +1,132 @@
+package httpc
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker fast fails requests to a host after consecutive failures until the reset timeout elapses, then lets a single
+// probe through to decide whether to close again. Transport errors and 5XX responses count as failures
+type circuitBreaker struct {
+	transport    http.RoundTripper
+	threshold    int
+	resetTimeout time.Duration
+
+	// now is swapped out in tests
+	now func() time.Time
+
+	mu    sync.Mutex
+	hosts map[string]*circuit
+}
+
+// circuit tracks the breaker state of a single host
+type circuit struct {
+	state    circuitState
+	failures int
+	openedAt time.Time
+}
+
+func newCircuitBreaker(transport http.RoundTripper, threshold int, resetTimeout time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		transport:    transport,
+		threshold:    threshold,
+		resetTimeout: resetTimeout,
+		now:          time.Now,
+		hosts:        make(map[string]*circuit),
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface, returning a CircuitOpen error without sending the request while the host's circuit is open
+func (b *circuitBreaker) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+
+	if !b.allow(host) {
+		return nil, &CircuitOpen{host}
+	}
+
+	resp, err := b.transport.RoundTrip(req)
+	if err != nil && req.Context().Err() != nil {
+		// a cancelled request says nothing about the host
+		b.release(host)
+		return resp, err
+	}
+
+	b.record(host, err == nil && resp.StatusCode < 500)
+
+	return resp, err
+}
+
+// allow reports whether a request to the host may be sent, moving an open circuit to half open once the reset timeout has elapsed
+func (b *circuitBreaker) allow(host string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(host)
+
+	switch c.state {
+	case circuitOpen:
+		if b.now().Sub(c.openedAt) < b.resetTimeout {
+			return false
+		}
+
+		c.state = circuitHalfOpen
+
+		return true
+	case circuitHalfOpen:
+		// only the probe is let through while half open
+		return false
+	}
+
+	return true
+}
+
+// record closes the host's circuit on success and opens it once failures reach the threshold or the half open probe fails
+func (b *circuitBreaker) record(host string, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(host)
+
+	if success {
+		c.state = circuitClosed
+		c.failures = 0
+
+		return
+	}
+
+	c.failures++
+
+	if c.state == circuitHalfOpen || c.failures >= b.threshold {
+		c.state = circuitOpen
+		c.openedAt = b.now()
+	}
+}
+
+// release reopens a half open circuit whose probe was cancelled, leaving the reset timeout elapsed so the next request probes again
+func (b *circuitBreaker) release(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c := b.circuit(host); c.state == circuitHalfOpen {
+		c.state = circuitOpen
+	}
+}
+
+func (b *circuitBreaker) circuit(host string) *circuit {
+	c, ok := b.hosts[host]
+	if !ok {
+		c = &circuit{}
+		b.hosts[host] = c
+	}
+
+	return c
+}
@@ -0,0 +1,127 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	var failing atomic.Bool
+	var hits atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	now := time.Unix(0, 0)
+
+	breaker := newCircuitBreaker(http.DefaultTransport, 2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	transport, err := NewRetryTransport(breaker, 1, WithRetryMaxDelay(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: transport}
+
+	get := func() error {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		return err
+	}
+
+	var circuitOpen *CircuitOpen
+
+	// closed: the attempt and its retry fail, reaching the threshold
+	failing.Store(true)
+	get()
+
+	if hits.Load() != 2 {
+		t.Fatalf("expected 2 requests while closed, got %d", hits.Load())
+	}
+
+	// open: requests fail fast without reaching the server
+	if err := get(); !errors.As(err, &circuitOpen) {
+		t.Fatalf("expected *CircuitOpen, got %v", err)
+	}
+
+	if hits.Load() != 2 {
+		t.Fatalf("expected the open breaker to short circuit, got %d requests", hits.Load())
+	}
+
+	// half open: after the reset timeout a successful probe closes the breaker
+	now = now.Add(time.Minute)
+	failing.Store(false)
+
+	for i := 0; i < 2; i++ {
+		if err := get(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if hits.Load() != 4 {
+		t.Fatalf("expected the closed breaker to let requests through, got %d requests", hits.Load())
+	}
+
+	// a failed half open probe reopens the breaker
+	failing.Store(true)
+	get()
+
+	now = now.Add(time.Minute)
+	before := hits.Load()
+	get()
+
+	if probes := hits.Load() - before; probes != 1 {
+		t.Fatalf("expected a single half open probe, got %d", probes)
+	}
+
+	if err := get(); !errors.As(err, &circuitOpen) {
+		t.Fatalf("expected the failed probe to reopen the breaker, got %v", err)
+	}
+}
+
+func TestCircuitBreakerShortCircuitsRetries(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cfg := &Config{BaseUrl: srv.URL, RetryEnabled: true, RetryOn5xx: true, RetryMax: 5, RetryMaxDelay: time.Millisecond}
+
+	client, err := NewClient(context.Background(), cfg, WithCircuitBreaker(3, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Get(context.Background(), "/", nil, nil)
+
+	var circuitOpen *CircuitOpen
+	if !errors.As(err, &circuitOpen) {
+		t.Fatalf("expected *CircuitOpen, got %v", err)
+	}
+
+	if hits.Load() != 3 {
+		t.Fatalf("expected the tripped breaker to stop the retry loop after 3 requests, got %d", hits.Load())
+	}
+}
@@ -0,0 +1,91 @@
+package httpc
+
+import (
+	"crypto/tls"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ConfigBuilder builds a Config with fluent setters, validating the values as they are set
+type ConfigBuilder struct {
+	cfg *Config
+	err error
+}
+
+// NewConfigBuilder creates a ConfigBuilder for the supplied base url
+func NewConfigBuilder(baseUrl string) *ConfigBuilder {
+	return &ConfigBuilder{
+		cfg: &Config{
+			BaseUrl: baseUrl,
+		},
+	}
+}
+
+// WithTimeout sets the client timeout
+func (b *ConfigBuilder) WithTimeout(timeout time.Duration) *ConfigBuilder {
+	if timeout < 0 {
+		return b.fail("timeout must not be negative, got " + timeout.String())
+	}
+
+	b.cfg.Timeout = int(timeout)
+
+	return b
+}
+
+// WithTLSConfig sets the TLS config used by the transport
+func (b *ConfigBuilder) WithTLSConfig(tlsConfig *tls.Config) *ConfigBuilder {
+	b.cfg.TlsConfig = tlsConfig
+	return b
+}
+
+// WithRetry enables retries with the supplied retry limit. A limit of zero uses DefaultRetryMax
+func (b *ConfigBuilder) WithRetry(limit int) *ConfigBuilder {
+	if limit < 0 {
+		return b.fail("retry limit must not be negative, got " + strconv.Itoa(limit))
+	}
+
+	b.cfg.RetryEnabled = true
+	b.cfg.RetryMax = limit
+
+	return b
+}
+
+// WithOTel enables Open Telemetry instrumentation
+func (b *ConfigBuilder) WithOTel() *ConfigBuilder {
+	b.cfg.OTelEnabled = true
+	return b
+}
+
+// WithRateLimit enables rate limiting with the supplied limit (per minute)
+func (b *ConfigBuilder) WithRateLimit(limit int) *ConfigBuilder {
+	if limit <= 0 {
+		return b.fail("rate limit must be positive, got " + strconv.Itoa(limit))
+	}
+
+	b.cfg.RateLimit = limit
+
+	return b
+}
+
+// Build validates the base url and returns the Config, or the first error encountered while building it
+func (b *ConfigBuilder) Build() (*Config, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	if _, err := url.ParseRequestURI(b.cfg.BaseUrl); err != nil {
+		return nil, &ConfigError{"invalid base url: " + err.Error()}
+	}
+
+	return b.cfg, nil
+}
+
+// fail records the first validation error, later errors are dropped
+func (b *ConfigBuilder) fail(msg string) *ConfigBuilder {
+	if b.err == nil {
+		b.err = &ConfigError{msg}
+	}
+
+	return b
+}
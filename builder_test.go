@@ -0,0 +1,60 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConfigBuilderValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *ConfigBuilder
+	}{
+		{"negative timeout", NewConfigBuilder("http://localhost").WithTimeout(-time.Second)},
+		{"negative retry limit", NewConfigBuilder("http://localhost").WithRetry(-1)},
+		{"zero rate limit", NewConfigBuilder("http://localhost").WithRateLimit(0)},
+		{"invalid base url", NewConfigBuilder("localhost")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.builder.Build()
+
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("expected *ConfigError, got %v", err)
+			}
+
+			if cfg != nil {
+				t.Fatal("expected no config on failure")
+			}
+		})
+	}
+}
+
+func TestConfigBuilder(t *testing.T) {
+	cfg, err := NewConfigBuilder("http://localhost").
+		WithTimeout(5 * time.Second).
+		WithRetry(2).
+		WithOTel().
+		WithRateLimit(60).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cfg.RetryEnabled || cfg.RetryMax != 2 || !cfg.OTelEnabled || cfg.RateLimit != 60 || time.Duration(cfg.Timeout) != 5*time.Second {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	client, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.RateLimiter == nil || client.timeout != 5*time.Second {
+		t.Fatal("expected the built config to configure the client")
+	}
+}
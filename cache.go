@@ -0,0 +1,415 @@
+package httpc
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheMaxBodyBytes is the largest response body cached when the client doesn't set a maximum response size
+const DefaultCacheMaxBodyBytes int64 = 1 << 20
+
+// cacheTransport serves GET responses from an in-memory cache, honoring their Cache-Control, Expires and Vary headers.
+// Stale entries with a validator are revalidated with a conditional request. Responses to requests with an Authorization header are
+// only served to requests with the same credentials
+type cacheTransport struct {
+	transport  http.RoundTripper
+	maxEntries int
+	now        func() time.Time
+
+	// maxBodyBytes caps the size of cached bodies, only responses with a known length up to it are stored
+	maxBodyBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+
+	// vary holds the request headers named by the last Vary header seen for each url with cached entries
+	vary map[string]*varyNames
+}
+
+// varyNames holds the Vary header names of a url along with the number of cached entries for it, so they are dropped with the last entry
+type varyNames struct {
+	names   []string
+	entries int
+}
+
+type cacheEntry struct {
+	key      string
+	url      string
+	vary     []string
+	status   string
+	code     int
+	header   http.Header
+	body     []byte
+	storedAt time.Time
+	lifetime time.Duration
+}
+
+func newCacheTransport(transport http.RoundTripper, maxEntries int, maxBodyBytes int64) *cacheTransport {
+	return &cacheTransport{
+		transport:    transport,
+		maxEntries:   maxEntries,
+		now:          time.Now,
+		maxBodyBytes: maxBodyBytes,
+		entries:      make(map[string]*list.Element),
+		lru:          list.New(),
+		vary:         make(map[string]*varyNames),
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface with response caching. Responses opened for streaming, such as with Stream or
+// DownloadFile, are passed through so their bodies are never read ahead
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !cacheableRequest(req) || isStreaming(req) {
+		return t.transport.RoundTrip(req)
+	}
+
+	key := t.cacheKey(req)
+	reqDirectives := parseCacheControl(req.Header.Get("Cache-Control"))
+
+	if _, ok := reqDirectives["no-store"]; ok {
+		return t.transport.RoundTrip(req)
+	}
+
+	entry := t.get(key)
+	if entry != nil {
+		_, noCache := reqDirectives["no-cache"]
+		if !noCache && t.now().Sub(entry.storedAt) < entry.lifetime {
+			return entry.response(req), nil
+		}
+
+		req = entry.conditional(req)
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry != nil && resp.StatusCode == http.StatusNotModified {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		return t.revalidated(entry, resp).response(req), nil
+	}
+
+	return t.store(req, resp)
+}
+
+// cacheKey combines the url with the values of the request headers the cached response varies on and the request credentials
+func (t *cacheTransport) cacheKey(req *http.Request) string {
+	url := req.URL.String()
+
+	var names []string
+
+	t.mu.Lock()
+	if vary, ok := t.vary[url]; ok {
+		names = vary.names
+	}
+	t.mu.Unlock()
+
+	return varyKey(url, req.Header, names)
+}
+
+func varyKey(url string, header http.Header, names []string) string {
+	var key strings.Builder
+	key.WriteString(url)
+
+	for _, name := range names {
+		key.WriteString("\n")
+		key.WriteString(name)
+		key.WriteString(": ")
+		key.WriteString(strings.Join(header.Values(name), ", "))
+	}
+
+	// the credentials are hashed so the cache doesn't hold on to them
+	if authorization := header.Get("Authorization"); authorization != "" {
+		sum := sha256.Sum256([]byte(authorization))
+
+		key.WriteString("\ncredentials: ")
+		key.WriteString(hex.EncodeToString(sum[:]))
+	}
+
+	return key.String()
+}
+
+// parseVary returns the canonical header names of a Vary header, reporting false for Vary: * since such a response can never be matched
+func parseVary(header http.Header) ([]string, bool) {
+	var names []string
+
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+
+			if name == "*" {
+				return nil, false
+			}
+
+			names = append(names, http.CanonicalHeaderKey(name))
+		}
+	}
+
+	return names, true
+}
+
+// cacheableRequest reports whether the request is eligible for caching. Requests carrying their own conditional or range headers are passed through
+func cacheableRequest(req *http.Request) bool {
+	if req.Method != http.MethodGet {
+		return false
+	}
+
+	for _, header := range []string{"Range", "If-None-Match", "If-Modified-Since"} {
+		if req.Header.Get(header) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (t *cacheTransport) get(key string) *cacheEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	element, ok := t.entries[key]
+	if !ok {
+		return nil
+	}
+
+	t.lru.MoveToFront(element)
+
+	return element.Value.(*cacheEntry)
+}
+
+func (t *cacheTransport) put(entry *cacheEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	vary, ok := t.vary[entry.url]
+	if !ok {
+		vary = &varyNames{}
+		t.vary[entry.url] = vary
+	}
+
+	vary.names = entry.vary
+
+	if element, ok := t.entries[entry.key]; ok {
+		element.Value = entry
+		t.lru.MoveToFront(element)
+		return
+	}
+
+	t.entries[entry.key] = t.lru.PushFront(entry)
+	vary.entries++
+
+	for t.maxEntries > 0 && t.lru.Len() > t.maxEntries {
+		t.evict(t.lru.Back())
+	}
+}
+
+func (t *cacheTransport) remove(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if element, ok := t.entries[key]; ok {
+		t.evict(element)
+	}
+}
+
+// evict drops the entry along with the Vary header names of its url once no other entry for the url remains. The lock must be held
+func (t *cacheTransport) evict(element *list.Element) {
+	entry := element.Value.(*cacheEntry)
+
+	t.lru.Remove(element)
+	delete(t.entries, entry.key)
+
+	if vary, ok := t.vary[entry.url]; ok {
+		vary.entries--
+		if vary.entries <= 0 {
+			delete(t.vary, entry.url)
+		}
+	}
+}
+
+// conditional clones the request with the validators of the cached entry so the server can respond with 304 Not Modified
+func (e *cacheEntry) conditional(req *http.Request) *http.Request {
+	etag := e.header.Get("ETag")
+	lastModified := e.header.Get("Last-Modified")
+
+	if etag == "" && lastModified == "" {
+		return req
+	}
+
+	req = req.Clone(req.Context())
+
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	return req
+}
+
+// store caches a successful response when its headers allow it, returning a response with a replayable body
+func (t *cacheTransport) store(req *http.Request, resp *http.Response) (*http.Response, error) {
+	url := req.URL.String()
+
+	lifetime, storable := freshness(resp.Header, t.now())
+	names, matchable := parseVary(resp.Header)
+	if !storable || !matchable {
+		t.remove(t.cacheKey(req))
+		return resp, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || resp.StatusCode == http.StatusPartialContent {
+		return resp, nil
+	}
+
+	// without a lifetime or a validator the entry could never be served
+	if lifetime <= 0 && resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "" {
+		return resp, nil
+	}
+
+	// bodies of unknown or excessive length are left unread rather than buffered in full
+	if resp.ContentLength < 0 || resp.ContentLength > t.maxBodyBytes {
+		t.remove(t.cacheKey(req))
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.put(&cacheEntry{
+		key:      varyKey(url, req.Header, names),
+		url:      url,
+		vary:     names,
+		status:   resp.Status,
+		code:     resp.StatusCode,
+		header:   resp.Header.Clone(),
+		body:     body,
+		storedAt: t.now(),
+		lifetime: lifetime,
+	})
+
+	return resp, nil
+}
+
+// revalidated refreshes a cached entry with the headers of a 304 Not Modified response
+func (t *cacheTransport) revalidated(entry *cacheEntry, resp *http.Response) *cacheEntry {
+	header := entry.header.Clone()
+	for key, values := range resp.Header {
+		header[key] = values
+	}
+
+	refreshed := &cacheEntry{
+		key:      entry.key,
+		url:      entry.url,
+		vary:     entry.vary,
+		status:   entry.status,
+		code:     entry.code,
+		header:   header,
+		body:     entry.body,
+		storedAt: t.now(),
+		lifetime: entry.lifetime,
+	}
+
+	if lifetime, ok := freshness(header, t.now()); ok {
+		refreshed.lifetime = lifetime
+	}
+
+	t.put(refreshed)
+
+	return refreshed
+}
+
+// response builds a new response for the cached entry with its own body reader
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        e.status,
+		StatusCode:    e.code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// freshness computes how long a response may be served from cache from its Cache-Control and Expires headers.
+// It reports false when the response must not be stored at all. Private responses aren't stored since the client may be shared across users
+func freshness(header http.Header, now time.Time) (time.Duration, bool) {
+	directives := parseCacheControl(header.Get("Cache-Control"))
+
+	for _, directive := range []string{"no-store", "private"} {
+		if _, ok := directives[directive]; ok {
+			return 0, false
+		}
+	}
+
+	if _, ok := directives["no-cache"]; ok {
+		return 0, true
+	}
+
+	if maxAge, ok := directives["max-age"]; ok {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil || seconds < 0 {
+			return 0, true
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			return 0, true
+		}
+
+		date := now
+		if parsed, err := http.ParseTime(header.Get("Date")); err == nil {
+			date = parsed
+		}
+
+		return expiresAt.Sub(date), true
+	}
+
+	return 0, true
+}
+
+// parseCacheControl splits a Cache-Control header into its lower cased directives and their values
+func parseCacheControl(header string) map[string]string {
+	directives := make(map[string]string)
+
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, value, _ := strings.Cut(part, "=")
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+
+	return directives
+}
@@ -0,0 +1,305 @@
+package httpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	var conditional int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		hits[r.URL.Path]++
+
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/nostore":
+			w.Header().Set("Cache-Control", "no-store")
+		case "/stale":
+			w.Header().Set("Cache-Control", "max-age=1")
+			w.Header().Set("ETag", `"v1"`)
+
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				conditional++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		w.Write([]byte(`{"name":"gopher"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithResponseCache(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	client.Http.Transport.(*cacheTransport).now = func() time.Time { return now }
+
+	get := func(resource string) {
+		var decoded struct{ Name string }
+		if _, err := client.Get(context.Background(), resource, nil, &decoded); err != nil || decoded.Name != "gopher" {
+			t.Fatalf("unexpected response for %s: %+v, %v", resource, decoded, err)
+		}
+	}
+
+	for _, resource := range []string{"/fresh", "/nostore", "/stale"} {
+		get(resource)
+		get(resource)
+	}
+
+	now = now.Add(2 * time.Second)
+	get("/stale")
+
+	if hits["/fresh"] != 1 || hits["/nostore"] != 2 || hits["/stale"] != 2 || conditional != 1 {
+		t.Fatalf("unexpected hits %v with %d conditional requests", hits, conditional)
+	}
+}
+
+func TestResponseCacheBodyLimits(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+
+		w.Header().Set("Cache-Control", "max-age=60")
+
+		switch r.URL.Path {
+		case "/chunked":
+			w.Write([]byte("part"))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("rest"))
+		case "/big":
+			w.Write([]byte(strings.Repeat("a", 100)))
+		default:
+			w.Write([]byte("small"))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithResponseCache(10), WithMaxResponseBytes(50))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		for _, resource := range []string{"/small", "/chunked"} {
+			if _, err := client.GetBytes(context.Background(), resource, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		client.GetBytes(context.Background(), "/big", nil)
+
+		reader, err := client.Stream(context.Background(), http.MethodGet, "/stream", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		io.Copy(io.Discard, reader)
+	}
+
+	expected := map[string]int{"/small": 1, "/chunked": 2, "/big": 2, "/stream": 2}
+	for resource, count := range expected {
+		if hits[resource] != count {
+			t.Fatalf("expected %d requests to %s, got %d", count, resource, hits[resource])
+		}
+	}
+}
+
+func TestResponseCacheVary(t *testing.T) {
+	var mu sync.Mutex
+	var hits, modifiedSince int
+	lastModified := time.Unix(1000, 0).UTC().Format(http.TimeFormat)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		hits++
+
+		switch r.URL.Path {
+		case "/vary":
+			w.Header().Set("Vary", "Accept-Language")
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Write([]byte(r.Header.Get("Accept-Language")))
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+			w.Write([]byte("private"))
+		case "/modified":
+			w.Header().Set("Last-Modified", lastModified)
+
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				modifiedSince++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			w.Write([]byte("modified"))
+		}
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: newCacheTransport(http.DefaultTransport, 10, DefaultCacheMaxBodyBytes)}
+
+	get := func(resource string, lang string) string {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+resource, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if lang != "" {
+			req.Header.Set("Accept-Language", lang)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(body)
+	}
+
+	for i := 0; i < 2; i++ {
+		for _, lang := range []string{"en", "fr"} {
+			if body := get("/vary", lang); body != lang {
+				t.Fatalf("expected the %s variant, got %q", lang, body)
+			}
+		}
+	}
+
+	if hits != 2 {
+		t.Fatalf("expected one request per variant, got %d", hits)
+	}
+
+	get("/private", "")
+	get("/private", "")
+
+	if hits != 4 {
+		t.Fatalf("expected private responses to bypass the cache, got %d requests", hits)
+	}
+
+	if get("/modified", "") != "modified" || get("/modified", "") != "modified" || modifiedSince != 1 {
+		t.Fatalf("expected a revalidation with If-Modified-Since, got %d", modifiedSince)
+	}
+}
+
+func TestResponseCacheVaryPruned(t *testing.T) {
+	var noStore atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "Accept")
+
+		if noStore.Load() {
+			w.Header().Set("Cache-Control", "no-store")
+		} else {
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	transport := newCacheTransport(http.DefaultTransport, 2, DefaultCacheMaxBodyBytes)
+	client := &http.Client{Transport: transport}
+
+	get := func(resource string) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+resource, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Cache-Control", "no-cache")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	for _, page := range []string{"1", "2", "3", "4", "5"} {
+		get("/items?page=" + page)
+	}
+
+	if len(transport.vary) != 2 {
+		t.Fatalf("expected the Vary names of evicted urls to be dropped, got %d", len(transport.vary))
+	}
+
+	// the entry is removed once the url stops being storable
+	noStore.Store(true)
+	get("/items?page=5")
+
+	if _, ok := transport.vary[srv.URL+"/items?page=5"]; ok || len(transport.vary) != 1 {
+		t.Fatalf("expected the Vary names of the removed url to be dropped, got %d", len(transport.vary))
+	}
+}
+
+func TestResponseCacheCredentials(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		token := r.Header.Get("Authorization")
+		hits[token]++
+
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte(`{"name":"` + token + `"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithResponseCache(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(token string) string {
+		var decoded struct{ Name string }
+		if _, err := client.Get(context.Background(), "/me", map[string]string{"Authorization": token}, &decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		return decoded.Name
+	}
+
+	for _, token := range []string{"Bearer alice", "Bearer bob", "Bearer alice", "Bearer bob"} {
+		if name := get(token); name != token {
+			t.Fatalf("expected the response for %q, got the response for %q", token, name)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if hits["Bearer alice"] != 1 || hits["Bearer bob"] != 1 {
+		t.Fatalf("expected one request per credential with the rest served from cache, got %v", hits)
+	}
+}
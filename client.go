@@ -4,20 +4,33 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"io"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
+	"path"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/throttled/throttled/v2"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
 const (
+	Version          string = "0.1.0"
+	DefaultUserAgent string = "httpc/" + Version
+
 	DefaultTimeout   int = 10
 	MaxRateLimitKeys int = 65536
 	MaxIdleConns     int = 100
@@ -31,14 +44,165 @@ type Config struct {
 	OTelEnabled  bool
 	RetryEnabled bool
 	RetryMax     int
+
+	// RateLimit configures a rate limiter with the supplied limit (per minute) when positive
+	RateLimit int
+
+	// RateLimitBurst is the number of requests, or total request cost, allowed at once before the rate limit applies, defaulting to 1
+	RateLimitBurst int
+
+	// RetryOnTimeout and RetryOn5xx limit retries to the enabled conditions. When neither is set every error, 429 and transient 5XX status code is retried
+	RetryOnTimeout bool
+	RetryOn5xx     bool
+
+	// RetryNonIdempotent retries POST and PATCH requests, which are otherwise never retried
+	RetryNonIdempotent bool
+
+	// RetryableStatusCodes replaces the default retried status codes of 429, 500, 502, 503 and 504 when neither retry condition is set
+	RetryableStatusCodes []int
+
+	// ShouldRetry replaces the built in retry conditions when set. It is called after every attempt with the number of attempts made so far
+	ShouldRetry func(req *http.Request, resp *http.Response, err error, attempt int) bool
+
+	// RetryPredicate replaces the built in retry conditions and ShouldRetry when set. The start of the response body is buffered so it can be read to decide
+	RetryPredicate func(req *http.Request, resp *http.Response, err error) bool
+
+	// OnRetry is called before each retry with the retry number and the response or error of the attempt being retried
+	OnRetry func(attempt int, req *http.Request, resp *http.Response, err error)
+
+	// RetryBodySpillThreshold is the request body size in bytes above which bodies are buffered to a temp file for replay instead of memory
+	RetryBodySpillThreshold int64
+
+	// RetryBodyLimit caps the size of request bodies buffered for replay when the request doesn't provide GetBody
+	RetryBodyLimit int64
+
+	// RetryAfterZero controls how a Retry-After value of zero is interpreted, defaulting to the exponential backoff
+	RetryAfterZero RetryAfterZeroPolicy
+
+	// RetryMaxDelay caps the backoff delay between retries, defaulting to DefaultRetryMaxDelay
+	RetryMaxDelay time.Duration
+
+	// RetryAttemptTimeout bounds each retry attempt separately from the overall timeout
+	RetryAttemptTimeout time.Duration
+
+	// RetryMaxElapsed stops retrying once the total time across attempts and backoff would exceed it
+	RetryMaxElapsed time.Duration
+
+	// RetryBackoff selects how the delay between retries grows, defaulting to BackoffFullJitter
+	RetryBackoff BackoffStrategy
+}
+
+// skipDefaultHeadersKey marks a request context whose request shouldn't receive the default headers
+type skipDefaultHeadersKey struct{}
+
+// streamingKey marks a request context whose response body is handed to the caller as it arrives, so transports mustn't read it ahead
+type streamingKey struct{}
+
+// isStreaming reports whether the request was opened for streaming
+func isStreaming(req *http.Request) bool {
+	streaming, _ := req.Context().Value(streamingKey{}).(bool)
+	return streaming
 }
 
+// requestCostKey holds the number of rate limiter tokens consumed by the request
+type requestCostKey struct{}
+
 type Client struct {
 	Http        *http.Client
 	Credentials *clientcredentials.Config
 	BaseUrl     *url.URL
 	RateLimiter *throttled.GCRARateLimiterCtx
 	Headers     map[string]string
+
+	// rateLimit, rateLimitStore, methodLimits and rateLimitBurst configure the rate limiters built once every option is applied
+	rateLimit      int
+	rateLimitStore throttled.GCRAStoreCtx
+	methodLimits   map[string]int
+	rateLimitBurst int
+
+	// methodLimiters override the rate limiter for requests with a matching method
+	methodLimiters map[string]*throttled.GCRARateLimiterCtx
+
+	// rateLimitKey computes the rate limiter key for a request in place of the base url
+	rateLimitKey func(method, resource string) string
+
+	// authenticate wraps the final http client once all options have been applied
+	authenticate   func(client *http.Client) *http.Client
+	authStyle      oauth2.AuthStyle
+	endpointParams url.Values
+
+	disableCompression bool
+	acceptGzip         bool
+	decompress         bool
+	maxRequestsPerConn int
+
+	maxConcurrentPerHost int
+
+	// requestPermits caps the number of in flight requests across every host
+	requestPermits chan struct{}
+
+	bodyPool *bufferPool
+
+	spanAttributes func(ctx context.Context) []attribute.KeyValue
+
+	spanNameFormatter func(operation string, req *http.Request) string
+	otelAttributes    []attribute.KeyValue
+
+	propagator propagation.TextMapPropagator
+
+	// basePath is joined in front of every resource path, with a leading slash and no trailing slash
+	basePath string
+
+	cacheEntries int
+
+	normalizePaths bool
+
+	observeMetrics func(metrics RequestMetrics)
+
+	requestHook  func(req *http.Request)
+	responseHook func(resp *http.Response, err error)
+
+	maxResponseBytes int64
+	bufferedDecode   bool
+	replayBodies     bool
+
+	dialTimeout time.Duration
+
+	proxy func(req *http.Request) (*url.URL, error)
+
+	middleware []func(http.RoundTripper) http.RoundTripper
+
+	breakerThreshold int
+	breakerReset     time.Duration
+
+	captureRedirects bool
+
+	validateRequest func(req *http.Request) error
+
+	jar http.CookieJar
+
+	clientCert *tls.Certificate
+	rootCAs    *x509.CertPool
+
+	decoder Decoder
+
+	// decoders maps media types to the decoder used for responses of that type in place of the default decoder
+	decoders map[string]Decoder
+
+	logger *slog.Logger
+
+	// redactedHeaders adds to the headers logged with their values replaced
+	redactedHeaders []string
+
+	rewriteUrl func(u *url.URL) (*url.URL, error)
+
+	deadlineHeader string
+
+	requiredHeaders []string
+
+	healthPath string
+
+	timeout time.Duration
 }
 
 // NewClient creates a new Client
@@ -53,18 +217,16 @@ func NewClient(ctx context.Context, cfg *Config, opts ...ClientOption) (*Client,
 		timeout = cfg.Timeout
 	}
 
-	var httpTransport http.RoundTripper
-	httpTransport, err = getRoundTripper(cfg, timeout)
-	if err != nil {
-		return nil, err
-	}
-
 	client := &Client{
 		BaseUrl: baseUrl,
-		Http: &http.Client{
-			Timeout:   time.Duration(timeout),
-			Transport: httpTransport,
-		},
+	}
+
+	if cfg.RateLimit > 0 {
+		opts = append([]ClientOption{WithRateLimiter(cfg.RateLimit)}, opts...)
+	}
+
+	if cfg.RateLimitBurst > 0 {
+		opts = append([]ClientOption{WithRateLimitBurst(cfg.RateLimitBurst)}, opts...)
 	}
 
 	for _, opt := range opts {
@@ -73,408 +235,826 @@ func NewClient(ctx context.Context, cfg *Config, opts ...ClientOption) (*Client,
 		}
 	}
 
+	if err := client.buildRateLimiters(); err != nil {
+		return nil, err
+	}
+
+	if !client.hasDefaultHeader("User-Agent") {
+		client.setDefaultHeader("User-Agent", DefaultUserAgent)
+	}
+
+	// the transport is built after the options are applied so transport level options take effect regardless of order
+	if client.Http == nil {
+		var httpTransport http.RoundTripper
+		httpTransport, err = getRoundTripper(cfg, client, timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		// the timeout is enforced through the request context rather than http.Client.Timeout so it can be overridden per request
+		client.timeout = time.Duration(timeout)
+		client.Http = &http.Client{
+			Transport: httpTransport,
+		}
+	} else {
+		// wrap a copy of the custom client so the config driven layers such as retries and tracing still apply without modifying the caller's client
+		custom := *client.Http
+
+		base := custom.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+
+		base, err = client.customTransport(base)
+		if err != nil {
+			return nil, err
+		}
+
+		custom.Transport, err = wrapRoundTripper(cfg, client, base)
+		if err != nil {
+			return nil, err
+		}
+
+		client.Http = &custom
+	}
+
+	// the authenticated client doesn't carry over the redirect policy, so it is captured beforehand
+	checkRedirect := client.Http.CheckRedirect
+
+	if client.authenticate != nil {
+		// token requests are made with the option's context rather than the request context, so the client timeout bounds them instead.
+		// Only token requests use the copy's timeout, api requests go through its transport
+		tokenClient := *client.Http
+		if tokenClient.Timeout == 0 {
+			tokenClient.Timeout = client.timeout
+		}
+
+		client.Http = client.authenticate(&tokenClient)
+	}
+
+	if client.captureRedirects {
+		client.Http.CheckRedirect = captureRedirects(checkRedirect)
+	}
+
+	// set on the final client so the jar survives a custom or authenticated client
+	if client.jar != nil {
+		client.Http.Jar = client.jar
+	}
+
 	return client, nil
 }
 
 // Get makes a GET request to the supplied endpoint and returns the response. If a struct pointer is supplied, the response body will be decoded into it
 func (c *Client) Get(ctx context.Context, resource string, headers map[string]string, decoded interface{}) (*http.Response, error) {
-	pathUrl, err := url.ParseRequestURI(resource)
+	return c.do(ctx, http.MethodGet, resource, nil, headers, decoded)
+}
+
+// GetWithParams makes a GET request to the supplied endpoint with the params merged into any query already present on the resource.
+// If a struct pointer is supplied, the response body will be decoded into it
+func (c *Client) GetWithParams(ctx context.Context, resource string, params url.Values, headers map[string]string, decoded interface{}) (*http.Response, error) {
+	resource, err := mergeQuery(resource, params)
 	if err != nil {
-		return nil, &InvalidResource{err}
+		return nil, err
 	}
 
-	fullUrl := c.BaseUrl.ResolveReference(pathUrl)
+	return c.do(ctx, http.MethodGet, resource, nil, headers, decoded)
+}
 
-	if c.RateLimiter != nil {
-		for {
-			limited, context, err := c.RateLimiter.RateLimitCtx(ctx, c.BaseUrl.String(), 1)
-			if err != nil {
-				return nil, err
-			}
+// GetClean makes a GET request like Get without applying the client's default headers. Only the supplied headers are sent
+func (c *Client) GetClean(ctx context.Context, resource string, headers map[string]string, decoded interface{}) (*http.Response, error) {
+	return c.do(context.WithValue(ctx, skipDefaultHeadersKey{}, true), http.MethodGet, resource, nil, headers, decoded)
+}
 
-			if limited {
-				time.Sleep(context.RetryAfter)
-				continue
-			}
+// Post makes a POST request to the supplied endpoint and returns the response. If a struct pointer is supplied, the response body will be decoded into it
+func (c *Client) Post(ctx context.Context, resource string, body io.Reader, headers map[string]string, decoded interface{}) (*http.Response, error) {
+	return c.do(ctx, http.MethodPost, resource, body, headers, decoded)
+}
 
-			break
-		}
+// PostWithCost makes a POST request like Post that consumes cost tokens from the rate limiter instead of one, for quotas that weight
+// endpoints differently. The cost must be at least one, failing with an InvalidRequest error otherwise, and can't exceed the rate limit burst set with WithRateLimitBurst
+func (c *Client) PostWithCost(ctx context.Context, resource string, body io.Reader, headers map[string]string, decoded interface{}, cost int) (*http.Response, error) {
+	if cost < 1 {
+		return nil, &InvalidRequest{errors.New("request cost must be at least 1, got " + strconv.Itoa(cost))}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullUrl.String(), nil)
+	return c.do(context.WithValue(ctx, requestCostKey{}, cost), http.MethodPost, resource, body, headers, decoded)
+}
+
+// Put makes a PUT request to the supplied endpoint and returns the response. If a struct pointer is supplied, the response body will be decoded into it
+func (c *Client) Put(ctx context.Context, resource string, body io.Reader, headers map[string]string, decoded interface{}) (*http.Response, error) {
+	return c.do(ctx, http.MethodPut, resource, body, headers, decoded)
+}
+
+// PostJSON makes a POST request like Post with the JSON encoding of in as the body, setting the Content-Type header to application/json
+// unless it is supplied. Encoding errors are returned as an EncodeError before any request is made
+func (c *Client) PostJSON(ctx context.Context, resource string, in interface{}, headers map[string]string, decoded interface{}) (*http.Response, error) {
+	return c.doJSON(ctx, http.MethodPost, resource, in, headers, decoded)
+}
+
+// PutJSON makes a PUT request like Put with the JSON encoding of in as the body, setting the Content-Type header to application/json
+// unless it is supplied. Encoding errors are returned as an EncodeError before any request is made
+func (c *Client) PutJSON(ctx context.Context, resource string, in interface{}, headers map[string]string, decoded interface{}) (*http.Response, error) {
+	return c.doJSON(ctx, http.MethodPut, resource, in, headers, decoded)
+}
+
+// doJSON encodes in as the request body and makes the request
+func (c *Client) doJSON(ctx context.Context, method string, resource string, in interface{}, headers map[string]string, decoded interface{}) (*http.Response, error) {
+	body, err := json.Marshal(in)
 	if err != nil {
-		return nil, err
+		return nil, &EncodeError{err}
 	}
 
-	for key, val := range c.Headers {
-		req.Header.Set(key, val)
-	}
+	reqHeaders := make(map[string]string, len(headers)+1)
+	reqHeaders["Content-Type"] = "application/json"
 
 	for key, val := range headers {
-		req.Header.Set(key, val)
+		if http.CanonicalHeaderKey(key) == "Content-Type" {
+			delete(reqHeaders, "Content-Type")
+		}
+
+		reqHeaders[key] = val
 	}
 
-	resp, err := c.Http.Do(req)
+	return c.do(ctx, method, resource, bytes.NewReader(body), reqHeaders, decoded)
+}
+
+// Delete makes a DELETE request to the supplied endpoint and returns the response. If a struct pointer is supplied, the response body will be decoded into it.
+// A request body is only sent when one is explicitly provided; pass nil to send a bodyless DELETE without a Content-Length header
+func (c *Client) Delete(ctx context.Context, resource string, body io.Reader, headers map[string]string, decoded interface{}) (*http.Response, error) {
+	return c.do(ctx, http.MethodDelete, resource, body, headers, decoded)
+}
+
+// Patch makes a PATCH request to the supplied endpoint and returns the response. If a struct pointer is supplied, the response body will be decoded into it
+func (c *Client) Patch(ctx context.Context, resource string, body io.Reader, headers map[string]string, decoded interface{}) (*http.Response, error) {
+	return c.do(ctx, http.MethodPatch, resource, body, headers, decoded)
+}
+
+// Head makes a HEAD request to the supplied endpoint and returns the response, allowing headers such as Content-Length to be read without downloading the body
+func (c *Client) Head(ctx context.Context, resource string, headers map[string]string) (*http.Response, error) {
+	return c.do(ctx, http.MethodHead, resource, nil, headers, nil)
+}
+
+// Options makes an OPTIONS request to the supplied endpoint and returns the response
+func (c *Client) Options(ctx context.Context, resource string, headers map[string]string) (*http.Response, error) {
+	return c.do(ctx, http.MethodOptions, resource, nil, headers, nil)
+}
+
+// Stream makes a request to the supplied endpoint and pipes the response body to the returned io.Reader. When library managed decompression
+// is enabled with WithAcceptGzip, gzip encoded bodies are decompressed as they arrive, so long running streams are decoded incrementally
+func (c *Client) Stream(ctx context.Context, method string, resource string, body io.Reader, headers map[string]string) (io.Reader, error) {
+	resp, err := c.open(ctx, method, resource, body, headers)
 	if err != nil {
-		return nil, &RequestError{err}
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode/10 != 20 {
-		errBody := &bytes.Buffer{}
-		resp.Write(errBody)
+	pr, pw := io.Pipe()
 
-		return nil, &BadStatusCode{errBody.String()}
-	}
+	// abort the copy when the context is cancelled so the goroutine doesn't linger on a body that won't close promptly
+	stop := context.AfterFunc(ctx, func() {
+		resp.Body.Close()
+		pw.CloseWithError(ctx.Err())
+	})
 
-	if decoded != nil {
-		err = json.NewDecoder(resp.Body).Decode(decoded)
-		if err != nil {
-			return nil, &DecodeError{err}
+	go func() {
+		defer stop()
+		defer resp.Body.Close()
+
+		// surface the copy error to the reader so a truncated body isn't mistaken for EOF, preferring the context error when the copy was aborted
+		_, err := io.Copy(pw, c.limitBody(resp.Body))
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
 		}
-	}
 
-	return resp, nil
+		pw.CloseWithError(err)
+	}()
+
+	return pr, nil
 }
 
-// Post makes a POST request to the supplied endpoint and returns the response. If a struct pointer is supplied, the response body will be decoded into it
-func (c *Client) Post(ctx context.Context, resource string, body io.Reader, headers map[string]string, decoded interface{}) (*http.Response, error) {
-	pathUrl, err := url.ParseRequestURI(resource)
+// StreamWithCancel makes a request like Stream and also returns a cancel func that aborts the request, closing the response body and stopping the copy.
+// The cancel func should be called once the caller is done with the stream to release its resources
+func (c *Client) StreamWithCancel(ctx context.Context, method string, resource string, body io.Reader, headers map[string]string) (io.Reader, func(), error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	reader, err := c.Stream(ctx, method, resource, body, headers)
 	if err != nil {
-		return nil, &InvalidResource{err}
+		cancel()
+		return nil, nil, err
 	}
 
-	fullUrl := c.BaseUrl.ResolveReference(pathUrl)
+	return reader, cancel, nil
+}
 
-	if c.RateLimiter != nil {
-		for {
-			limited, context, err := c.RateLimiter.RateLimitCtx(ctx, c.BaseUrl.String(), 1)
-			if err != nil {
-				return nil, err
-			}
+// StreamTo makes a request to the supplied endpoint and copies the decoded response body to dst, returning the number of bytes written.
+// Errors encountered while copying are returned as is
+func (c *Client) StreamTo(ctx context.Context, method string, resource string, body io.Reader, headers map[string]string, dst io.Writer) (int64, error) {
+	resp, err := c.open(ctx, method, resource, body, headers)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
 
-			if limited {
-				time.Sleep(context.RetryAfter)
-				continue
-			}
+	return io.Copy(dst, c.limitBody(resp.Body))
+}
 
-			break
-		}
-	}
+// GetString makes a GET request to the supplied endpoint and returns the response body as a string. When the body exceeds the maximum response size,
+// the body read up to the limit is returned along with a ResponseTooLarge error
+func (c *Client) GetString(ctx context.Context, resource string, headers map[string]string) (string, *http.Response, error) {
+	body, resp, err := c.readBody(ctx, http.MethodGet, resource, headers)
+	return string(body), resp, err
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fullUrl.String(), body)
+// GetBytes makes a GET request to the supplied endpoint and returns the response body, closing it once read. When the body exceeds the
+// maximum response size, a ResponseTooLarge error is returned without the partial body
+func (c *Client) GetBytes(ctx context.Context, resource string, headers map[string]string) ([]byte, error) {
+	body, _, err := c.readBody(ctx, http.MethodGet, resource, headers)
 	if err != nil {
 		return nil, err
 	}
 
-	for key, val := range c.Headers {
-		req.Header.Set(key, val)
-	}
+	return body, nil
+}
 
-	for key, val := range headers {
-		req.Header.Set(key, val)
+// DoRequest sends a prebuilt request using the client's transport, rate limiting and error handling. Default headers are only applied
+// when the request doesn't already set them and the rate limiter is keyed on the request host, sharing the key of the verb helpers for requests
+// to the base url host. If a struct pointer is supplied, the response body will be decoded into it
+func (c *Client) DoRequest(req *http.Request, decoded interface{}) (*http.Response, error) {
+	key := req.URL.Host
+	if c.BaseUrl != nil && req.URL.Host == c.BaseUrl.Host {
+		key = c.BaseUrl.String()
 	}
 
-	resp, err := c.Http.Do(req)
-	if err != nil {
-		return nil, &RequestError{err}
+	if c.rateLimitKey != nil {
+		key = c.rateLimitKey(req.Method, req.URL.RequestURI())
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode/10 != 20 {
-		errBody := &bytes.Buffer{}
-		resp.Write(errBody)
+	if err := c.wait(req.Context(), req.Method, key); err != nil {
+		return nil, err
+	}
 
-		return nil, &BadStatusCode{errBody.String()}
+	req = req.Clone(req.Context())
+	if req.Header == nil {
+		req.Header = make(http.Header)
 	}
 
-	if decoded != nil {
-		err = json.NewDecoder(resp.Body).Decode(decoded)
-		if err != nil {
-			return nil, &DecodeError{err}
+	for key, val := range c.Headers {
+		if req.Header.Get(key) == "" {
+			req.Header.Set(key, val)
 		}
 	}
 
-	return resp, nil
+	if err := c.validate(req); err != nil {
+		return nil, err
+	}
+
+	return c.send(req, decoded)
 }
 
-// Put makes a PUT request to the supplied endpoint and returns the response. If a struct pointer is supplied, the response body will be decoded into it
-func (c *Client) Put(ctx context.Context, resource string, body io.Reader, headers map[string]string, decoded interface{}) (*http.Response, error) {
-	pathUrl, err := url.ParseRequestURI(resource)
+// do makes a request to the supplied endpoint and returns the response. If a struct pointer is supplied, the response body will be decoded into it
+func (c *Client) do(ctx context.Context, method string, resource string, body io.Reader, headers map[string]string, decoded interface{}) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, resource, body, headers)
 	if err != nil {
-		return nil, &InvalidResource{err}
+		return nil, err
 	}
 
-	fullUrl := c.BaseUrl.ResolveReference(pathUrl)
+	return c.send(req, decoded)
+}
 
-	if c.RateLimiter != nil {
-		for {
-			limited, context, err := c.RateLimiter.RateLimitCtx(ctx, c.BaseUrl.String(), 1)
-			if err != nil {
-				return nil, err
-			}
+// open makes a request to the supplied endpoint and returns the response with its body left open for streaming
+func (c *Client) open(ctx context.Context, method string, resource string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	req, err := c.newRequest(context.WithValue(ctx, streamingKey{}, true), method, resource, body, headers)
+	if err != nil {
+		return nil, err
+	}
 
-			if limited {
-				time.Sleep(context.RetryAfter)
-				continue
-			}
+	return c.execute(req)
+}
 
-			break
-		}
+// readBody makes a request to the supplied endpoint and reads the response body up to the maximum response size before closing it
+func (c *Client) readBody(ctx context.Context, method string, resource string, headers map[string]string) ([]byte, *http.Response, error) {
+	req, err := c.newRequest(ctx, method, resource, nil, headers)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, fullUrl.String(), body)
+	resp, err := c.execute(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	defer resp.Body.Close()
 
-	for key, val := range c.Headers {
-		req.Header.Set(key, val)
-	}
+	body, err := io.ReadAll(c.limitBody(resp.Body))
+	if err != nil {
+		if _, ok := err.(*ResponseTooLarge); !ok {
+			return nil, nil, &RequestError{err}
+		}
 
-	for key, val := range headers {
-		req.Header.Set(key, val)
+		return body, resp, err
 	}
 
-	resp, err := c.Http.Do(req)
+	return body, resp, nil
+}
+
+// send makes the request and decodes the response body into decoded when supplied. The body is closed unless it was buffered for replay
+func (c *Client) send(req *http.Request, decoded interface{}) (*http.Response, error) {
+	resp, err := c.execute(req)
 	if err != nil {
-		return nil, &RequestError{err}
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode/10 != 20 {
-		errBody := &bytes.Buffer{}
-		resp.Write(errBody)
+	var body io.Reader = resp.Body
+
+	// decode from a separate reader so the buffered body can still be read in full by the caller
+	if c.replayBodies {
+		if err := c.bufferBody(resp); err != nil {
+			return nil, err
+		}
 
-		return nil, &BadStatusCode{errBody.String()}
+		body, _ = ReplayBody(resp)
 	}
+	defer resp.Body.Close()
 
 	if decoded != nil {
-		err = json.NewDecoder(resp.Body).Decode(decoded)
+		decoder, ok := c.decoderFor(resp)
+		if !ok {
+			return nil, unexpectedContentType(resp, body)
+		}
+
+		err = c.decode(body, decoder, decoded)
 		if err != nil {
-			return nil, &DecodeError{err}
+			return nil, err
 		}
 	}
 
 	return resp, nil
 }
 
-// Delete makes a DELETE request to the supplied endpoint and returns the response. If a struct pointer is supplied, the response body will be decoded into it
-func (c *Client) Delete(ctx context.Context, resource string, body io.Reader, headers map[string]string, decoded interface{}) (*http.Response, error) {
-	pathUrl, err := url.ParseRequestURI(resource)
+// execute makes the request and returns the response with its body open. Non 2XX responses are closed and returned as a BadStatusCode error
+// and successful responses missing a required header as a MissingHeader error
+func (c *Client) execute(req *http.Request) (*http.Response, error) {
+	resp, err := c.roundTrip(req)
 	if err != nil {
-		return nil, &InvalidResource{err}
+		return nil, &RequestError{err}
 	}
 
-	fullUrl := c.BaseUrl.ResolveReference(pathUrl)
-
-	if c.RateLimiter != nil {
-		for {
-			limited, context, err := c.RateLimiter.RateLimitCtx(ctx, c.BaseUrl.String(), 1)
-			if err != nil {
-				return nil, err
-			}
-
-			if limited {
-				time.Sleep(context.RetryAfter)
-				continue
-			}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
 
-			break
-		}
+		return nil, newBadStatusCode(resp, c.bodyPool)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fullUrl.String(), body)
-	if err != nil {
+	if err := c.checkRequiredHeaders(resp); err != nil {
+		resp.Body.Close()
 		return nil, err
 	}
 
-	for key, val := range c.Headers {
-		req.Header.Set(key, val)
+	return resp, nil
+}
+
+// doHooked sends the request, calling the request hook just before and the response hook just after, regardless of the status code.
+// Retries happen within the transport, so the hooks run once per call
+func (c *Client) doHooked(req *http.Request) (*http.Response, error) {
+	if c.captureRedirects {
+		req = withRedirectLog(req)
 	}
 
-	for key, val := range headers {
-		req.Header.Set(key, val)
+	if c.requestHook != nil {
+		c.requestHook(req)
 	}
 
-	resp, err := c.Http.Do(req)
+	release, err := c.acquireRequest(req.Context())
 	if err != nil {
-		return nil, &RequestError{err}
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode/10 != 20 {
-		errBody := &bytes.Buffer{}
-		resp.Write(errBody)
+	resp, err := c.Http.Do(req)
 
-		return nil, &BadStatusCode{errBody.String()}
+	if c.responseHook != nil {
+		c.responseHook(resp, err)
 	}
 
-	if decoded != nil {
-		err = json.NewDecoder(resp.Body).Decode(decoded)
-		if err != nil {
-			return nil, &DecodeError{err}
-		}
+	if err != nil {
+		release()
+		return nil, err
 	}
 
+	// the permit is held until the body is closed, so a response still being read counts as in flight
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+
 	return resp, nil
 }
 
-// Patch makes a PATCH request to the supplied endpoint and returns the response. If a struct pointer is supplied, the response body will be decoded into it
-func (c *Client) Patch(ctx context.Context, resource string, body io.Reader, headers map[string]string, decoded interface{}) (*http.Response, error) {
-	pathUrl, err := url.ParseRequestURI(resource)
-	if err != nil {
-		return nil, &InvalidResource{err}
+// checkRequiredHeaders returns a MissingHeader error for the first required header absent from the response
+func (c *Client) checkRequiredHeaders(resp *http.Response) error {
+	for _, name := range c.requiredHeaders {
+		if resp.Header.Get(name) == "" {
+			return &MissingHeader{name}
+		}
 	}
 
-	fullUrl := c.BaseUrl.ResolveReference(pathUrl)
+	return nil
+}
 
-	if c.RateLimiter != nil {
-		for {
-			limited, context, err := c.RateLimiter.RateLimitCtx(ctx, c.BaseUrl.String(), 1)
-			if err != nil {
-				return nil, err
-			}
+// newRequest resolves the supplied resource against the base url, waits on the rate limiter and builds a request with the default and supplied headers
+func (c *Client) newRequest(ctx context.Context, method string, resource string, body io.Reader, headers map[string]string) (*http.Request, error) {
+	fullUrl, err := c.resolve(resource)
+	if err != nil {
+		return nil, err
+	}
 
-			if limited {
-				time.Sleep(context.RetryAfter)
-				continue
-			}
+	key := c.BaseUrl.String()
+	if c.rateLimitKey != nil {
+		key = c.rateLimitKey(method, resource)
+	}
 
-			break
-		}
+	if err := c.wait(ctx, method, key); err != nil {
+		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, fullUrl.String(), body)
+	req, err := http.NewRequestWithContext(ctx, method, fullUrl.String(), body)
 	if err != nil {
 		return nil, err
 	}
 
-	for key, val := range c.Headers {
-		req.Header.Set(key, val)
+	if skip, _ := ctx.Value(skipDefaultHeadersKey{}).(bool); !skip {
+		for key, val := range c.Headers {
+			req.Header.Set(key, val)
+		}
 	}
 
 	for key, val := range headers {
 		req.Header.Set(key, val)
 	}
 
-	resp, err := c.Http.Do(req)
+	if err := c.validate(req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// validate runs the request validator, returning its error as an InvalidRequest error
+func (c *Client) validate(req *http.Request) error {
+	if c.validateRequest == nil {
+		return nil
+	}
+
+	if err := c.validateRequest(req); err != nil {
+		return &InvalidRequest{err}
+	}
+
+	return nil
+}
+
+// resolve parses the supplied resource and resolves it against the base url, applying the url rewriter when set
+func (c *Client) resolve(resource string) (*url.URL, error) {
+	pathUrl, err := parseResource(resource)
 	if err != nil {
-		return nil, &RequestError{err}
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode/10 != 20 {
-		errBody := &bytes.Buffer{}
-		resp.Write(errBody)
+	// absolute urls are left as is, only paths resolved against the base url are prefixed
+	if c.basePath != "" && pathUrl.Host == "" {
+		pathUrl.Path = joinBasePath(c.basePath, pathUrl.Path)
 
-		return nil, &BadStatusCode{errBody.String()}
+		if pathUrl.RawPath != "" {
+			pathUrl.RawPath = joinBasePath((&url.URL{Path: c.basePath}).EscapedPath(), pathUrl.RawPath)
+		}
 	}
 
-	if decoded != nil {
-		err = json.NewDecoder(resp.Body).Decode(decoded)
+	fullUrl := c.BaseUrl.ResolveReference(pathUrl)
+	if (fullUrl.Scheme != "http" && fullUrl.Scheme != "https") || fullUrl.Host == "" {
+		return nil, &InvalidResource{errors.New("resource doesn't resolve to an http url: " + resource)}
+	}
+
+	if c.normalizePaths {
+		normalizePath(fullUrl)
+	}
+
+	if c.rewriteUrl != nil {
+		fullUrl, err = c.rewriteUrl(fullUrl)
 		if err != nil {
-			return nil, &DecodeError{err}
+			return nil, &InvalidResource{err}
+		}
+
+		if fullUrl == nil {
+			return nil, &InvalidResource{errors.New("url rewriter returned a nil url")}
 		}
 	}
 
-	return resp, nil
+	return fullUrl, nil
 }
 
-// Stream makes a request to the supplied endpoint and pipes the response body to the returned io.Reader
-func (c *Client) Stream(ctx context.Context, method string, resource string, body io.Reader, headers map[string]string) (io.Reader, error) {
-	pathUrl, err := url.ParseRequestURI(resource)
+// parseResource parses a resource as a url reference. Relative references such as users?active=true are resolved against the base url
+// as a browser would, apart from paths starting with a double slash, which are treated as paths rather than a reference to another host
+func parseResource(resource string) (*url.URL, error) {
+	parse := url.Parse
+	if strings.HasPrefix(resource, "//") {
+		parse = url.ParseRequestURI
+	}
+
+	pathUrl, err := parse(resource)
 	if err != nil {
 		return nil, &InvalidResource{err}
 	}
 
-	fullUrl := c.BaseUrl.ResolveReference(pathUrl)
+	return pathUrl, nil
+}
 
-	if c.RateLimiter != nil {
-		for {
-			limited, context, err := c.RateLimiter.RateLimitCtx(ctx, c.BaseUrl.String(), 1)
-			if err != nil {
-				return nil, err
-			}
+// joinBasePath prefixes the path with the base path unless it already starts with it
+func joinBasePath(basePath string, resourcePath string) string {
+	if resourcePath == basePath || strings.HasPrefix(resourcePath, basePath+"/") {
+		return resourcePath
+	}
 
-			if limited {
-				time.Sleep(context.RetryAfter)
-				continue
-			}
+	return basePath + "/" + strings.TrimPrefix(resourcePath, "/")
+}
 
-			break
-		}
+// mergeQuery adds the supplied params to the query of the resource, keeping any values already present
+func mergeQuery(resource string, params url.Values) (string, error) {
+	if len(params) == 0 {
+		return resource, nil
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, fullUrl.String(), body)
+	pathUrl, err := parseResource(resource)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	for key, val := range c.Headers {
-		req.Header.Set(key, val)
+	query := pathUrl.Query()
+	for key, vals := range params {
+		for _, val := range vals {
+			query.Add(key, val)
+		}
 	}
 
-	for key, val := range headers {
-		req.Header.Set(key, val)
+	pathUrl.RawQuery = query.Encode()
+
+	return pathUrl.String(), nil
+}
+
+// normalizePath collapses duplicate slashes and resolves dot segments without escaping the root, preserving a trailing slash.
+// The query and fragment are left untouched
+func normalizePath(u *url.URL) {
+	escaped := u.EscapedPath()
+	if escaped == "" {
+		return
 	}
 
-	resp, err := c.Http.Do(req)
+	cleaned := path.Clean("/" + escaped)
+	if strings.HasSuffix(escaped, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+
+	unescaped, err := url.PathUnescape(cleaned)
 	if err != nil {
-		return nil, &RequestError{err}
+		return
 	}
 
-	if resp.StatusCode/10 != 20 {
-		errBody := &bytes.Buffer{}
-		resp.Write(errBody)
-		resp.Body.Close()
+	u.Path = unescaped
+	u.RawPath = cleaned
+}
 
-		return nil, &BadStatusCode{errBody.String()}
+// wait blocks until the rate limiter for the method allows a request for the supplied key, returning early when the context is done
+func (c *Client) wait(ctx context.Context, method string, key string) error {
+	rateLimiter := c.RateLimiter
+	if methodLimiter, ok := c.methodLimiters[method]; ok {
+		rateLimiter = methodLimiter
+		key = method + " " + key
 	}
 
-	pr, pw := io.Pipe()
+	if rateLimiter == nil {
+		return nil
+	}
 
-	go func() {
-		defer resp.Body.Close()
-		defer pw.Close()
+	cost := 1
+	if requestCost, ok := ctx.Value(requestCostKey{}).(int); ok {
+		cost = requestCost
+	}
 
-		io.Copy(pw, resp.Body)
-	}()
+	for {
+		limited, result, err := rateLimiter.RateLimitCtx(ctx, key, cost)
+		if err != nil {
+			return &RateLimitError{err}
+		}
 
-	return pr, nil
+		if !limited {
+			return nil
+		}
+
+		// the limiter reports a negative retry after when the cost can never fit within its burst
+		if result.RetryAfter < 0 {
+			return &RateLimitError{errors.New("request cost of " + strconv.Itoa(cost) + " exceeds the rate limiter burst")}
+		}
+
+		// fail fast rather than sleeping until a deadline that will pass before the limiter allows the request
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < result.RetryAfter {
+			return &RateLimitError{context.DeadlineExceeded}
+		}
+
+		timer := time.NewTimer(result.RetryAfter)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &RateLimitError{ctx.Err()}
+		case <-timer.C:
+		}
+	}
 }
 
-func getRoundTripper(cfg *Config, timeout int) (http.RoundTripper, error) {
+func getRoundTripper(cfg *Config, client *Client, timeout int) (http.RoundTripper, error) {
 	var transport http.RoundTripper
-	var err error
+
+	dialer := &net.Dialer{
+		Timeout: time.Duration(timeout),
+	}
+
+	if client.dialTimeout > 0 {
+		dialer.Timeout = client.dialTimeout
+	}
+
+	dial := dialer.DialContext
+	if client.maxRequestsPerConn > 0 {
+		dial = countConns(dial)
+	}
 
 	defaultTransport := &http.Transport{
-		Dial: (&net.Dialer{
-			Timeout: time.Duration(timeout),
-		}).Dial,
-		TLSClientConfig:     cfg.TlsConfig,
+		DialContext:         dial,
+		TLSClientConfig:     client.clientTLSConfig(cfg.TlsConfig),
 		MaxIdleConns:        MaxIdleConns,
 		MaxConnsPerHost:     MaxConnsPerHost,
 		MaxIdleConnsPerHost: MaxConnsPerHost,
 		IdleConnTimeout:     time.Duration(timeout),
 		TLSHandshakeTimeout: time.Duration(timeout),
+		DisableCompression:  client.disableCompression,
+		Proxy:               client.proxy,
 	}
 
 	transport = defaultTransport
 
+	if client.maxRequestsPerConn > 0 {
+		transport = &connLimitTransport{transport, int64(client.maxRequestsPerConn)}
+	}
+
+	return wrapRoundTripper(cfg, client, transport)
+}
+
+// clientTLSConfig adds the client certificate and CA bundle of WithClientCert to a clone of the supplied config, so the caller's config isn't modified
+func (c *Client) clientTLSConfig(tlsConfig *tls.Config) *tls.Config {
+	if c.clientCert == nil {
+		return tlsConfig
+	}
+
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	} else {
+		tlsConfig = tlsConfig.Clone()
+	}
+
+	tlsConfig.Certificates = append(tlsConfig.Certificates, *c.clientCert)
+
+	if c.rootCAs != nil {
+		tlsConfig.RootCAs = c.rootCAs
+	}
+
+	return tlsConfig
+}
+
+// customTransport applies the dial timeout, proxy and client certificate options to a clone of a custom client's transport. They can only
+// be applied to an *http.Transport, so any other transport fails with a ConfigError rather than silently dropping them
+func (c *Client) customTransport(transport http.RoundTripper) (http.RoundTripper, error) {
+	if c.dialTimeout <= 0 && c.proxy == nil && c.clientCert == nil {
+		return transport, nil
+	}
+
+	httpTransport, ok := transport.(*http.Transport)
+	if !ok {
+		return nil, &ConfigError{"dial timeout, proxy and client certificate options require the custom client to use an *http.Transport"}
+	}
+
+	httpTransport = httpTransport.Clone()
+
+	if c.dialTimeout > 0 {
+		dialer := &net.Dialer{Timeout: c.dialTimeout}
+		httpTransport.DialContext = dialer.DialContext
+	}
+
+	if c.proxy != nil {
+		httpTransport.Proxy = c.proxy
+	}
+
+	httpTransport.TLSClientConfig = c.clientTLSConfig(httpTransport.TLSClientConfig)
+
+	return httpTransport, nil
+}
+
+// wrapRoundTripper wraps the supplied transport with the concurrency, metrics, compression, middleware, circuit breaker, retry, cache and tracing layers enabled by the config and options
+func wrapRoundTripper(cfg *Config, client *Client, transport http.RoundTripper) (http.RoundTripper, error) {
+	if client.maxConcurrentPerHost > 0 {
+		transport = newConcurrencyTransport(transport, client.maxConcurrentPerHost)
+	}
+
+	if client.observeMetrics != nil {
+		transport = &metricsTransport{transport, client.observeMetrics}
+	}
+
+	if client.acceptGzip {
+		transport = &gzipTransport{transport}
+	}
+
+	if client.decompress {
+		transport = &decompressTransport{transport}
+	}
+
+	// logged beneath the middleware so the log shows the request as sent
+	if client.logger != nil {
+		transport = newLoggingTransport(transport, client.logger, client.redactedHeaders)
+	}
+
+	// the first middleware is the outermost, each sits beneath the retry layer so it sees every attempt
+	for i := len(client.middleware) - 1; i >= 0; i-- {
+		transport = client.middleware[i](transport)
+	}
+
+	if client.breakerThreshold > 0 {
+		transport = newCircuitBreaker(transport, client.breakerThreshold, client.breakerReset)
+	}
+
 	if cfg.RetryEnabled {
-		transport, err = NewRetryTransport(defaultTransport, cfg.RetryMax)
+		// the predicate never peeks past the size the client would read
+		peekLimit := DefaultRetryPredicatePeekLimit
+		if client.maxResponseBytes > 0 {
+			peekLimit = min(peekLimit, client.maxResponseBytes)
+		}
+
+		retryTransport, err := NewRetryTransport(
+			transport,
+			cfg.RetryMax,
+			WithRetryOnTimeout(cfg.RetryOnTimeout),
+			WithRetryOn5xx(cfg.RetryOn5xx),
+			WithRetryBodySpillThreshold(cfg.RetryBodySpillThreshold),
+			WithRetryBodyLimit(cfg.RetryBodyLimit),
+			WithRetryNonIdempotent(cfg.RetryNonIdempotent),
+			WithRetryableStatusCodes(cfg.RetryableStatusCodes...),
+			WithShouldRetry(cfg.ShouldRetry),
+			WithRetryPredicate(cfg.RetryPredicate),
+			WithRetryPredicatePeekLimit(peekLimit),
+			WithOnRetry(cfg.OnRetry),
+			WithRetryAfterZeroHandling(cfg.RetryAfterZero),
+			WithRetryMaxDelay(cfg.RetryMaxDelay),
+			WithBackoffStrategy(cfg.RetryBackoff),
+			WithAttemptTimeout(cfg.RetryAttemptTimeout),
+			WithRetryMaxElapsed(cfg.RetryMaxElapsed),
+		)
 		if err != nil {
 			return nil, err
 		}
+
+		// the otel transport wraps the retry transport, so its span is available in the request context
+		retryTransport.recordRetries = cfg.OTelEnabled
+
+		transport = retryTransport
+	}
+
+	if client.cacheEntries > 0 {
+		maxBodyBytes := DefaultCacheMaxBodyBytes
+		if client.maxResponseBytes > 0 {
+			maxBodyBytes = client.maxResponseBytes
+		}
+
+		transport = newCacheTransport(transport, client.cacheEntries, maxBodyBytes)
 	}
 
 	if cfg.OTelEnabled {
-		transport = otelhttp.NewTransport(
-			transport,
+		if client.spanAttributes != nil {
+			transport = &spanAttributesTransport{transport, client.spanAttributes}
+		}
+
+		otelOpts := []otelhttp.Option{
 			otelhttp.WithTracerProvider(otel.GetTracerProvider()),
 			otelhttp.WithMeterProvider(otel.GetMeterProvider()),
-		)
+		}
+
+		if client.spanNameFormatter != nil {
+			otelOpts = append(otelOpts, otelhttp.WithSpanNameFormatter(client.spanNameFormatter))
+		}
+
+		if len(client.otelAttributes) > 0 {
+			otelOpts = append(otelOpts, otelhttp.WithSpanOptions(trace.WithAttributes(client.otelAttributes...)))
+		}
+
+		// the global propagator is a no-op unless the application sets one, so the trace context and baggage are always injected by default
+		propagator := client.propagator
+		if propagator == nil {
+			propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+		}
+
+		otelOpts = append(otelOpts, otelhttp.WithPropagators(propagator))
+
+		transport = otelhttp.NewTransport(transport, otelOpts...)
+	} else if client.propagator != nil {
+		transport = &propagationTransport{transport, client.propagator}
 	}
 
 	return transport, nil
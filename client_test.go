@@ -0,0 +1,972 @@
+package httpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPostWithCost(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RateLimit: 6, RateLimitBurst: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var invalid *InvalidRequest
+	if _, err := client.PostWithCost(context.Background(), "/", nil, nil, nil, 0); !errors.As(err, &invalid) || errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected an InvalidRequest error for a zero cost, got %v", err)
+	}
+
+	if _, err := client.PostWithCost(context.Background(), "/", nil, nil, nil, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	// the cost 5 request left one token of the per minute budget
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.Get(ctx, "/", nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the budget to be spent, got %v", err)
+	}
+
+	if _, err := client.PostWithCost(context.Background(), "/", nil, nil, nil, 7); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected a cost above the burst to fail, got %v", err)
+	}
+
+	spaced, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RateLimit: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := spaced.PostWithCost(context.Background(), "/", nil, nil, nil, 2); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected a cost above the default burst of 1 to fail, got %v", err)
+	}
+}
+
+func TestRateLimitBurst(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithRateLimitBurst(1), WithRateLimiter(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.Get(ctx, "/", nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a burst of 1 to space requests, got %v", err)
+	}
+
+	if _, err := client.PostWithCost(context.Background(), "/", nil, nil, nil, 2); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected a cost above the burst to fail, got %v", err)
+	}
+
+	if _, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithRateLimitBurst(0)); err == nil {
+		t.Fatal("expected a burst below 1 to be rejected")
+	}
+}
+
+func TestRelativeResources(t *testing.T) {
+	var requested string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.RequestURI()
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL + "/api/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resources := map[string]string{
+		"users?active=true": "/api/users?active=true",
+		"./sub/path":        "/api/sub/path",
+		"users#frag":        "/api/users",
+		"/abs":              "/abs",
+		"../up":             "/up",
+		"":                  "/api/",
+	}
+
+	for resource, expected := range resources {
+		if _, err := client.Get(context.Background(), resource, nil, nil); err != nil {
+			t.Fatalf("%q: %v", resource, err)
+		}
+
+		if requested != expected {
+			t.Errorf("expected %q to resolve to %s, got %s", resource, expected, requested)
+		}
+	}
+
+	for _, resource := range []string{"::bad", "http://[::1", "mailto:a@b", "%zz"} {
+		var invalid *InvalidResource
+		if _, err := client.Get(context.Background(), resource, nil, nil); !errors.As(err, &invalid) {
+			t.Errorf("expected an InvalidResource error for %q, got %v", resource, err)
+		}
+	}
+}
+
+func TestGetWithParamsRelative(t *testing.T) {
+	var requested string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.RequestURI()
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL + "/api/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GetWithParams(context.Background(), "users?a=1", url.Values{"b": {"2"}}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if requested != "/api/users?a=1&b=2" {
+		t.Fatalf("expected the params to be merged into the relative resource, got %s", requested)
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	reader, err := client.Stream(ctx, http.MethodGet, "/", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.ReadFull(reader, make([]byte, 5)); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	if _, err := io.ReadAll(reader); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the reader to return the context error, got %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the copy goroutine to exit, %d goroutines remain of %d", runtime.NumGoroutine(), before)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDoRequest(t *testing.T) {
+	var received http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.Write([]byte(`{"name":"gopher"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: "http://unused.invalid"},
+		WithDefaultHeaders(map[string]string{"X-Set": "default", "X-Default": "default"}),
+		WithRateLimiter(600),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Set", "mine")
+
+	var out struct{ Name string }
+	if _, err := client.DoRequest(req, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Name != "gopher" {
+		t.Fatalf("expected the body to be decoded, got %+v", out)
+	}
+
+	if received.Get("X-Set") != "mine" || received.Get("X-Default") != "default" {
+		t.Fatalf("expected default headers without clobbering set ones, got %v", received)
+	}
+
+	if req.Header.Get("X-Default") != "" {
+		t.Fatal("expected the caller's request to be left unmodified")
+	}
+}
+
+func TestPathNormalization(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		resource string
+		want     string
+	}{
+		{"duplicate slashes", "http://localhost", "//users//1?filter=a//b#x//y", "http://localhost/users/1?filter=a//b#x//y"},
+		{"trailing slash base", "http://localhost/api/", "/users//", "http://localhost/users/"},
+		{"dot segments", "http://localhost", "/users/../../groups/./1", "http://localhost/groups/1"},
+		{"escaped slash", "http://localhost", "/a%2Fb//c", "http://localhost/a%2Fb/c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(context.Background(), &Config{BaseUrl: tt.base}, WithPathNormalization())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resolved, err := client.resolve(tt.resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resolved.String() != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, resolved)
+			}
+		})
+	}
+}
+
+// failingWriter is an io.Writer that always fails with err
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestStreamTo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world"))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	written, err := client.StreamTo(context.Background(), http.MethodGet, "/", nil, nil, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if written != 11 || buf.String() != "hello world" {
+		t.Fatalf("expected 11 bytes of hello world, got %d bytes %q", written, buf.String())
+	}
+
+	errWrite := errors.New("disk full")
+	if _, err := client.StreamTo(context.Background(), http.MethodGet, "/", nil, nil, failingWriter{errWrite}); !errors.Is(err, errWrite) {
+		t.Fatalf("expected the copy error, got %v", err)
+	}
+}
+
+func TestVerbs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"method":"` + r.Method + `"}`))
+	}))
+	defer srv.Close()
+
+	type verb func(client *Client, ctx context.Context, decoded interface{}) (*http.Response, error)
+
+	verbs := map[string]verb{
+		http.MethodGet: func(client *Client, ctx context.Context, decoded interface{}) (*http.Response, error) {
+			return client.Get(ctx, "/", nil, decoded)
+		},
+		http.MethodPost: func(client *Client, ctx context.Context, decoded interface{}) (*http.Response, error) {
+			return client.Post(ctx, "/", strings.NewReader(`{}`), nil, decoded)
+		},
+		http.MethodPut: func(client *Client, ctx context.Context, decoded interface{}) (*http.Response, error) {
+			return client.Put(ctx, "/", strings.NewReader(`{}`), nil, decoded)
+		},
+		http.MethodDelete: func(client *Client, ctx context.Context, decoded interface{}) (*http.Response, error) {
+			return client.Delete(ctx, "/", nil, nil, decoded)
+		},
+		http.MethodPatch: func(client *Client, ctx context.Context, decoded interface{}) (*http.Response, error) {
+			return client.Patch(ctx, "/", strings.NewReader(`{}`), nil, decoded)
+		},
+	}
+
+	for method, do := range verbs {
+		t.Run(method, func(t *testing.T) {
+			client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithRateLimiter(1), WithRateLimitBurst(1))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decoded struct{ Method string }
+			if _, err := do(client, context.Background(), &decoded); err != nil {
+				t.Fatal(err)
+			}
+
+			if decoded.Method != method {
+				t.Fatalf("expected a %s request, got %q", method, decoded.Method)
+			}
+
+			// the burst is spent, so the next request can't be allowed before the deadline
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			_, err = do(client, ctx, nil)
+
+			var rateLimitErr *RateLimitError
+			if !errors.As(err, &rateLimitErr) || !errors.Is(err, ErrRateLimited) {
+				t.Fatalf("expected a wrapped rate limit error, got %v", err)
+			}
+		})
+	}
+
+	// Stream shares the same front half as the verb methods
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := client.Stream(context.Background(), http.MethodPatch, "/", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := io.ReadAll(reader); string(data) != `{"method":"PATCH"}` {
+		t.Fatalf("unexpected stream body %q", data)
+	}
+}
+
+func TestURLRewriter(t *testing.T) {
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+	}))
+	defer srv.Close()
+
+	rewriter := func(u *url.URL) (*url.URL, error) {
+		if u.Path == "/forbidden" {
+			return nil, errors.New("forbidden path")
+		}
+
+		u.Path = "/v2" + u.Path
+
+		return u, nil
+	}
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithURLRewriter(rewriter))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/users", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "/v2/users" {
+		t.Fatalf("expected the request to hit the rewritten path, got %s", path)
+	}
+
+	_, err = client.Get(context.Background(), "/forbidden", nil, nil)
+
+	var invalid *InvalidResource
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected *InvalidResource when the rewriter fails, got %v", err)
+	}
+}
+
+func TestRateLimitWaitContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RateLimit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err = client.Get(ctx, "/", nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a context deadline error, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the call to return promptly, took %s", elapsed)
+	}
+}
+
+func TestHeadAndOptions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		w.Header().Set("Content-Length", "1234")
+
+		if r.Method == http.MethodGet {
+			w.Write(make([]byte, 1234))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Head(context.Background(), "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.ContentLength != 1234 || resp.Header.Get("Content-Length") != "1234" {
+		t.Fatalf("expected a readable Content-Length of 1234, got %d", resp.ContentLength)
+	}
+
+	resp, err = client.Options(context.Background(), "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Header.Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Fatalf("unexpected Allow header %q", resp.Header.Get("Allow"))
+	}
+
+	_, err = client.Head(context.Background(), "/missing", nil)
+
+	var statusErr *BadStatusCode
+	if !errors.As(err, &statusErr) || statusErr.StatusCode() != http.StatusNotFound {
+		t.Fatalf("expected a 404 status error for a bodyless response, got %v", err)
+	}
+}
+
+func TestGetString(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world"))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, resp, err := client.GetString(context.Background(), "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body != "hello world" || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected hello world with status 200, got %q with %d", body, resp.StatusCode)
+	}
+
+	limited, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithMaxResponseBytes(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, resp, err = limited.GetString(context.Background(), "/", nil)
+
+	var tooLarge *ResponseTooLarge
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("expected *ResponseTooLarge, got %v", err)
+	}
+
+	if body != "hello" || resp == nil {
+		t.Fatalf("expected the truncated body and the response, got %q", body)
+	}
+}
+
+func TestGetWithParams(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(`{"a":2}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := url.Values{
+		"tag": {"a b", "c&d"},
+		"foo": {"baz"},
+		"q":   {"=?/#"},
+	}
+
+	var decoded struct{ A int }
+	if _, err := client.GetWithParams(context.Background(), "/search?foo=bar", params, nil, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.A != 2 {
+		t.Fatalf("expected the body to be decoded, got %+v", decoded)
+	}
+
+	if tags := query["tag"]; len(tags) != 2 || tags[0] != "a b" || tags[1] != "c&d" {
+		t.Fatalf("expected both tag values to survive encoding, got %v", tags)
+	}
+
+	if foo := query["foo"]; len(foo) != 2 || foo[0] != "bar" || foo[1] != "baz" {
+		t.Fatalf("expected the existing query to be merged rather than clobbered, got %v", foo)
+	}
+
+	if query.Get("q") != "=?/#" {
+		t.Fatalf("expected reserved characters to be escaped, got %q", query.Get("q"))
+	}
+}
+
+func TestStreamTruncated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := client.Stream(context.Background(), http.MethodGet, "/", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.ReadAll(reader); err == nil || err == io.EOF {
+		t.Fatalf("expected the truncated body to surface a non-EOF error, got %v", err)
+	}
+}
+
+func TestStreamWithCancel(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		close(done)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, cancel, err := client.StreamWithCancel(context.Background(), http.MethodGet, "/", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.ReadFull(reader, make([]byte, 5)); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	if _, err := io.ReadAll(reader); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the stream to terminate with context.Canceled, got %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected cancel to tear down the request")
+	}
+}
+
+func TestGetClean(t *testing.T) {
+	var received http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithDefaultHeaders(map[string]string{"X-Default": "1"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if received.Get("X-Default") != "1" {
+		t.Fatalf("expected default headers on a normal call, got %v", received)
+	}
+
+	if _, err := client.GetClean(context.Background(), "/", map[string]string{"X-Call": "2"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if received.Get("X-Default") != "" || received.Get("X-Call") != "2" {
+		t.Fatalf("expected only the per request headers on a clean call, got %v", received)
+	}
+}
+
+func TestGet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/invalid" {
+			w.Write([]byte(`{"name":`))
+			return
+		}
+
+		w.Write([]byte(`{"name":"gopher"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct{ Name string }
+	if _, err := client.Get(context.Background(), "/", nil, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Name != "gopher" {
+		t.Fatalf("expected the body to be decoded, got %+v", decoded)
+	}
+
+	resp, err := client.Get(context.Background(), "/", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200 without decoding, got %d", resp.StatusCode)
+	}
+
+	if _, err := client.Get(context.Background(), "/invalid", nil, &decoded); !errors.Is(err, ErrDecode) {
+		t.Fatalf("expected ErrDecode for an invalid body, got %v", err)
+	}
+}
+
+func TestStreamRateLimitWait(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RateLimit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err = client.Stream(ctx, http.MethodGet, "/", nil, nil)
+
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a rate limit error for the deadline, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("expected the stream to fail fast rather than wait out the deadline, took %s", elapsed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.Stream(ctx, http.MethodGet, "/", nil, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled context to return promptly, got %v", err)
+	}
+}
+
+func TestSuccessStatusRange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, err := strconv.Atoi(r.URL.Path[1:])
+		if err != nil {
+			code = http.StatusBadRequest
+		}
+
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, code := range []int{http.StatusOK, http.StatusNoContent, http.StatusPartialContent, http.StatusIMUsed, 299} {
+		resource := "/" + strconv.Itoa(code)
+
+		if _, err := client.Get(context.Background(), resource, nil, nil); err != nil {
+			t.Fatalf("expected status %d to succeed, got %v", code, err)
+		}
+
+		if result := client.Request(context.Background(), RequestSpec{Method: http.MethodGet, Resource: resource}); !result.IsSuccess() {
+			t.Fatalf("expected status %d to be a successful result, got %v", code, result.Err)
+		}
+	}
+
+	for _, code := range []int{http.StatusMultipleChoices, http.StatusNotFound} {
+		if _, err := client.Get(context.Background(), "/"+strconv.Itoa(code), nil, nil); err == nil {
+			t.Fatalf("expected status %d to fail", code)
+		}
+	}
+}
+
+func TestPostAndPutJSON(t *testing.T) {
+	var mu sync.Mutex
+	var method, contentType string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		method, contentType = r.Method, r.Header.Get("Content-Type")
+		mu.Unlock()
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		json.NewEncoder(w).Encode(body)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := func() (string, string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return method, contentType
+	}
+
+	type payload struct {
+		Name string `json:"name"`
+	}
+
+	var out payload
+
+	if _, err := client.PostJSON(context.Background(), "/", payload{Name: "httpc"}, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Name != "httpc" {
+		t.Fatalf("expected the echoed name, got %q", out.Name)
+	}
+
+	if m, ct := last(); m != http.MethodPost || ct != "application/json" {
+		t.Fatalf("expected POST with application/json, got %s with %q", m, ct)
+	}
+
+	if _, err := client.PutJSON(context.Background(), "/", payload{Name: "httpc"}, map[string]string{"Content-Type": "application/vnd.api+json"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if m, ct := last(); m != http.MethodPut || ct != "application/vnd.api+json" {
+		t.Fatalf("expected PUT with the caller's content type, got %s with %q", m, ct)
+	}
+
+	var encodeErr *EncodeError
+
+	if _, err := client.PostJSON(context.Background(), "/", make(chan int), nil, nil); !errors.As(err, &encodeErr) {
+		t.Fatalf("expected *EncodeError, got %v", err)
+	}
+}
+
+func TestGetBytes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hello":
+			w.Write([]byte("hello"))
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 100)))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithMaxResponseBytes(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := client.GetBytes(context.Background(), "/hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != "hello" {
+		t.Fatalf("expected %q, got %q", "hello", body)
+	}
+
+	body, err = client.GetBytes(context.Background(), "/empty", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body == nil || len(body) != 0 {
+		t.Fatalf("expected a non-nil empty slice, got %#v", body)
+	}
+
+	body, err = client.GetBytes(context.Background(), "/large", nil)
+
+	var tooLarge *ResponseTooLarge
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("expected *ResponseTooLarge, got %v", err)
+	}
+
+	if body != nil {
+		t.Fatalf("expected no body on error, got %d bytes", len(body))
+	}
+}
+
+func TestBaseURLPath(t *testing.T) {
+	var mu sync.Mutex
+	var requestURI string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		requestURI = r.URL.RequestURI()
+	}))
+	defer srv.Close()
+
+	last := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return requestURI
+	}
+
+	tests := []struct {
+		prefix   string
+		resource string
+		want     string
+	}{
+		{prefix: "/api/v2", resource: "/users", want: "/api/v2/users"},
+		{prefix: "/api/v2/", resource: "/users", want: "/api/v2/users"},
+		{prefix: "api/v2", resource: "/users?page=1", want: "/api/v2/users?page=1"},
+		{prefix: "/api/v2", resource: "/api/v2/users", want: "/api/v2/users"},
+		{prefix: "/api/v2", resource: "/api/v2", want: "/api/v2"},
+		{prefix: "/api/v2", resource: "/api/v20", want: "/api/v2/api/v20"},
+		{prefix: "/api/v2", resource: "/", want: "/api/v2/"},
+		{prefix: "/api/v2", resource: "/a%2Fb", want: "/api/v2/a%2Fb"},
+		{prefix: "/", resource: "/users", want: "/users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix+" "+tt.resource, func(t *testing.T) {
+			client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithBaseURLPath(tt.prefix))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := client.Get(context.Background(), tt.resource, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := last(); got != tt.want {
+				t.Fatalf("expected request uri %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("absolute resource", func(t *testing.T) {
+		client, err := NewClient(context.Background(), &Config{BaseUrl: "http://example.invalid"}, WithBaseURLPath("/api"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := client.Get(context.Background(), srv.URL+"/absolute", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := last(); got != "/absolute" {
+			t.Fatalf("expected absolute urls to skip the base path, got %s", got)
+		}
+	})
+}
+
+func TestDoRequestSharesRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RateLimit: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.DoRequest(req, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DoRequest to share the limit spent by Get, got %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package httpc
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// gzipTransport requests gzip encoded responses and decompresses them itself. It is only installed when the
+// underlying transport has compression disabled, so responses are never decompressed twice
+type gzipTransport struct {
+	transport http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface with library managed gzip decompression
+func (t *gzipTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// like the standard transport, range requests aren't compressed since the range would apply to the compressed bytes
+	if req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if !hasEncodedBody(req, resp) || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return resp, nil
+	}
+
+	decompressed(resp, []string{"gzip"})
+
+	return resp, nil
+}
+
+// decompressTransport decodes gzip and deflate encoded responses that the underlying transport left compressed, such as when
+// the caller set its own Accept-Encoding header
+type decompressTransport struct {
+	transport http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface, decoding every encoding listed in the Content-Encoding header
+func (t *decompressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if !hasEncodedBody(req, resp) {
+		return resp, nil
+	}
+
+	encodings, ok := parseContentEncoding(resp.Header.Get("Content-Encoding"))
+	if !ok || len(encodings) == 0 {
+		return resp, nil
+	}
+
+	decompressed(resp, encodings)
+
+	return resp, nil
+}
+
+// hasEncodedBody reports whether the response has a body the transport hasn't already decompressed
+func hasEncodedBody(req *http.Request, resp *http.Response) bool {
+	return !resp.Uncompressed && resp.Body != nil && resp.Body != http.NoBody && req.Method != http.MethodHead
+}
+
+// parseContentEncoding returns the listed encodings, skipping identity. It reports false when an encoding can't be decoded
+func parseContentEncoding(header string) ([]string, bool) {
+	var encodings []string
+
+	for _, encoding := range strings.Split(header, ",") {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+
+		switch encoding {
+		case "", "identity":
+		case "gzip", "x-gzip", "deflate":
+			encodings = append(encodings, encoding)
+		default:
+			return nil, false
+		}
+	}
+
+	return encodings, true
+}
+
+// decompressed replaces the response body with one that decodes the supplied encodings and drops the headers describing the encoded body
+func decompressed(resp *http.Response, encodings []string) {
+	resp.Body = &decompressBody{body: resp.Body, encodings: encodings}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+// decompressBody lazily wraps a response body with a decompressor for each encoding on the first read. Encodings are
+// listed in the order they were applied, so they are decoded in reverse
+type decompressBody struct {
+	body      io.ReadCloser
+	encodings []string
+	r         io.Reader
+	closers   []io.Closer
+	err       error
+}
+
+func (b *decompressBody) Read(p []byte) (int, error) {
+	if b.r == nil && b.err == nil {
+		b.r, b.err = b.decoder()
+	}
+
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	return b.r.Read(p)
+}
+
+func (b *decompressBody) decoder() (io.Reader, error) {
+	var r io.Reader = b.body
+
+	for i := len(b.encodings) - 1; i >= 0; i-- {
+		var rc io.ReadCloser
+		var err error
+
+		switch b.encodings[i] {
+		case "gzip", "x-gzip":
+			rc, err = gzip.NewReader(r)
+		case "deflate":
+			rc, err = newDeflateReader(r)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		b.closers = append(b.closers, rc)
+		r = rc
+	}
+
+	return r, nil
+}
+
+func (b *decompressBody) Close() error {
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		b.closers[i].Close()
+	}
+
+	return b.body.Close()
+}
+
+// newDeflateReader decodes zlib wrapped deflate data as the spec requires, falling back to raw deflate data which some servers send instead
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+
+	header, err := br.Peek(2)
+	if err != nil && len(header) < 2 {
+		return flate.NewReader(br), nil
+	}
+
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+
+	return flate.NewReader(br), nil
+}
@@ -0,0 +1,207 @@
+package httpc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// gzipHandler serves a JSON body, gzip encoded when the request accepts it, recording the Accept-Encoding header of the last request
+func gzipHandler(acceptEncoding *string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "application/json")
+
+		if !strings.Contains(*acceptEncoding, "gzip") {
+			w.Write([]byte(`{"name":"gopher"}`))
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`{"name":"gopher"}`))
+		gz.Close()
+	}
+}
+
+func TestAcceptGzip(t *testing.T) {
+	var acceptEncoding string
+	srv := httptest.NewServer(gzipHandler(&acceptEncoding))
+	defer srv.Close()
+
+	tests := []struct {
+		name           string
+		opts           []ClientOption
+		acceptEncoding string
+	}{
+		{"transport managed", nil, "gzip"},
+		{"library managed", []ClientOption{WithAcceptGzip(true)}, "gzip"},
+		{"disabled", []ClientOption{WithAcceptGzip(false)}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RetryEnabled: true}, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decoded struct{ Name string }
+			if _, err := client.Get(context.Background(), "/", nil, &decoded); err != nil {
+				t.Fatal(err)
+			}
+
+			if decoded.Name != "gopher" {
+				t.Fatalf("expected the body to be decompressed once, got %+v", decoded)
+			}
+
+			if acceptEncoding != tt.acceptEncoding {
+				t.Fatalf("expected Accept-Encoding %q, got %q", tt.acceptEncoding, acceptEncoding)
+			}
+		})
+	}
+}
+
+func TestStreamGzip(t *testing.T) {
+	release := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("expected Accept-Encoding gzip, got %q", r.Header.Get("Accept-Encoding"))
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte("event one\n"))
+		zw.Flush()
+		w.(http.Flusher).Flush()
+
+		// hold the rest of the stream back until the first event has been decoded
+		<-release
+
+		zw.Write([]byte("event two\n"))
+		zw.Close()
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithAcceptGzip(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := client.Stream(context.Background(), http.MethodGet, "/", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buffered := bufio.NewReader(reader)
+
+	line, err := buffered.ReadString('\n')
+	if err != nil || line != "event one\n" {
+		t.Fatalf("expected the first event to be decoded while the stream is open, got %q, %v", line, err)
+	}
+
+	release <- struct{}{}
+
+	rest, err := io.ReadAll(buffered)
+	if err != nil || string(rest) != "event two\n" {
+		t.Fatalf("expected the second event, got %q, %v", rest, err)
+	}
+
+	release <- struct{}{}
+
+	var buf bytes.Buffer
+	if _, err := client.StreamTo(context.Background(), http.MethodGet, "/", nil, nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != "event one\nevent two\n" {
+		t.Fatalf("expected StreamTo to write the decoded body, got %q", buf.String())
+	}
+}
+
+// compress encodes data with the writer returned by newWriter
+func compress(t *testing.T, data []byte, newWriter func(w io.Writer) (io.WriteCloser, error)) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w, err := newWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.Write(data)
+	w.Close()
+
+	return buf.Bytes()
+}
+
+func TestDecompression(t *testing.T) {
+	gzipWriter := func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }
+	zlibWriter := func(w io.Writer) (io.WriteCloser, error) { return zlib.NewWriter(w), nil }
+	flateWriter := func(w io.Writer) (io.WriteCloser, error) { return flate.NewWriter(w, flate.DefaultCompression) }
+
+	payload := []byte(`{"a":7}`)
+
+	encoded := map[string]struct {
+		encoding string
+		body     []byte
+	}{
+		"/gzip":     {"gzip", compress(t, payload, gzipWriter)},
+		"/zlib":     {"deflate", compress(t, payload, zlibWriter)},
+		"/flate":    {"deflate", compress(t, payload, flateWriter)},
+		"/multiple": {"deflate, gzip", compress(t, compress(t, payload, zlibWriter), gzipWriter)},
+		"/identity": {"identity", payload},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := encoded[r.URL.Path]
+		w.Header().Set("Content-Encoding", response.encoding)
+		w.Write(response.body)
+	}))
+	defer srv.Close()
+
+	// a custom Accept-Encoding disables the transport's own decompression
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithDecompression(), WithDefaultHeaders(map[string]string{"Accept-Encoding": "gzip, deflate"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for resource := range encoded {
+		t.Run(resource, func(t *testing.T) {
+			var decoded struct{ A int }
+
+			resp, err := client.Get(context.Background(), resource, nil, &decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if decoded.A != 7 {
+				t.Fatalf("expected the decompressed body to decode, got %+v", decoded)
+			}
+
+			if resource != "/identity" && resp.Header.Get("Content-Encoding") != "" {
+				t.Fatalf("expected Content-Encoding to be removed, got %q", resp.Header.Get("Content-Encoding"))
+			}
+
+			reader, err := client.Stream(context.Background(), http.MethodGet, resource, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if data, err := io.ReadAll(reader); err != nil || !bytes.Equal(data, payload) {
+				t.Fatalf("expected the stream to be decompressed, got %q, %v", data, err)
+			}
+		})
+	}
+}
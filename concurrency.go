@@ -0,0 +1,92 @@
+package httpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// concurrencyTransport caps the number of in flight requests to each host. A request holds its permit until the response body is closed
+type concurrencyTransport struct {
+	transport http.RoundTripper
+	perHost   int
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+func newConcurrencyTransport(transport http.RoundTripper, perHost int) *concurrencyTransport {
+	return &concurrencyTransport{
+		transport: transport,
+		perHost:   perHost,
+		hosts:     make(map[string]chan struct{}),
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface, waiting for a permit for the request host before sending it
+func (t *concurrencyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sem := t.semaphore(req.URL.Host)
+
+	select {
+	case sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	release := func() { <-sem }
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+
+	return resp, nil
+}
+
+// semaphore returns the semaphore for the supplied host, creating it on first use
+func (t *concurrencyTransport) semaphore(host string) chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sem, ok := t.hosts[host]
+	if !ok {
+		sem = make(chan struct{}, t.perHost)
+		t.hosts[host] = sem
+	}
+
+	return sem
+}
+
+// releaseBody releases a concurrency permit once the response body is closed
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+
+	return err
+}
+
+// acquireRequest waits for one of the client wide request permits set with WithMaxConcurrentRequests, returning its release func or the
+// context error when the context is done first
+func (c *Client) acquireRequest(ctx context.Context) (func(), error) {
+	if c.requestPermits == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case c.requestPermits <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return func() { <-c.requestPermits }, nil
+}
@@ -0,0 +1,133 @@
+package httpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// peakServer starts a server that holds each request for delay and records the peak number of requests in flight
+func peakServer(delay time.Duration) (*httptest.Server, *atomic.Int64) {
+	var current, peak atomic.Int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+
+		time.Sleep(delay)
+	}))
+
+	return srv, &peak
+}
+
+func TestMaxConcurrentPerHost(t *testing.T) {
+	slow, slowPeak := peakServer(200 * time.Millisecond)
+	defer slow.Close()
+
+	fast, fastPeak := peakServer(20 * time.Millisecond)
+	defer fast.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: slow.URL}, WithMaxConcurrentPerHost(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var slowWg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		slowWg.Add(1)
+		go func() {
+			defer slowWg.Done()
+
+			if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	// let the slow host take its permits before the fast host is called
+	time.Sleep(20 * time.Millisecond)
+
+	start := time.Now()
+
+	var fastWg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		fastWg.Add(1)
+		go func() {
+			defer fastWg.Done()
+
+			req, err := http.NewRequest(http.MethodGet, fast.URL, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if _, err := client.DoRequest(req, nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	fastWg.Wait()
+
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("expected the fast host not to be starved by the slow one, took %s", elapsed)
+	}
+
+	slowWg.Wait()
+
+	if slowPeak.Load() != 2 || fastPeak.Load() != 2 {
+		t.Fatalf("expected each host to peak at 2 requests in flight, got %d and %d", slowPeak.Load(), fastPeak.Load())
+	}
+}
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	srv, peak := peakServer(20 * time.Millisecond)
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithMaxConcurrentRequests(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := peak.Load(); n == 0 || n > 3 {
+		t.Fatalf("expected at most 3 requests in flight, got %d", n)
+	}
+
+	single, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithMaxConcurrentRequests(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// hold the only permit so the next request has to wait for it
+	single.requestPermits <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := single.Get(ctx, "/", nil, nil); err == nil {
+		t.Fatal("expected the context to expire while waiting for a permit")
+	}
+}
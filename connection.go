@@ -0,0 +1,63 @@
+package httpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+)
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// countedConn tracks the number of requests served over a connection
+type countedConn struct {
+	net.Conn
+	served atomic.Int64
+}
+
+// countConns wraps the supplied dial func so every connection it opens tracks the requests it serves
+func countConns(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return &countedConn{Conn: conn}, nil
+	}
+}
+
+// connLimitTransport closes connections once they have served the maximum number of requests, forcing a re-dial
+type connLimitTransport struct {
+	transport   http.RoundTripper
+	maxRequests int64
+}
+
+// RoundTrip implements the http.RoundTripper interface, marking the request that exhausts its connection with Connection: close
+func (t *connLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var limited *http.Request
+
+	trace := &httptrace.ClientTrace{
+		// GotConn is called before the request is written, so the request can still ask for the connection to be closed
+		GotConn: func(info httptrace.GotConnInfo) {
+			conn := info.Conn
+			if tlsConn, ok := conn.(interface{ NetConn() net.Conn }); ok {
+				conn = tlsConn.NetConn()
+			}
+
+			counted, ok := conn.(*countedConn)
+			if !ok {
+				return
+			}
+
+			if counted.served.Add(1) >= t.maxRequests {
+				limited.Close = true
+			}
+		},
+	}
+
+	limited = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	return t.transport.RoundTrip(limited)
+}
@@ -0,0 +1,40 @@
+package httpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMaxRequestsPerConnection(t *testing.T) {
+	var conns atomic.Int64
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	srv.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithMaxRequestsPerConnection(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 7; i++ {
+		if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+
+	if got := conns.Load(); got != 3 {
+		t.Fatalf("expected 3 connections for 7 requests, got %d", got)
+	}
+}
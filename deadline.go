@@ -0,0 +1,51 @@
+package httpc
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultDeadlineHeader string = "X-Deadline"
+
+// ResponseDeadline reads the deadline header of the supplied response, which defaults to X-Deadline. The header may be an RFC 3339 or HTTP-date
+// timestamp, or a number of seconds relative to the response Date header. It reports false when the header is absent or malformed
+func (c *Client) ResponseDeadline(resp *http.Response) (time.Time, bool) {
+	if resp == nil {
+		return time.Time{}, false
+	}
+
+	name := c.deadlineHeader
+	if name == "" {
+		name = DefaultDeadlineHeader
+	}
+
+	return parseDeadline(resp.Header, name, time.Now())
+}
+
+// parseDeadline parses the named header as a timestamp or as seconds relative to the Date header, falling back to now when the Date header is missing
+func parseDeadline(header http.Header, name string, now time.Time) (time.Time, bool) {
+	value := strings.TrimSpace(header.Get(name))
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if date, err := http.ParseTime(header.Get("Date")); err == nil {
+			now = date
+		}
+
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+
+	if deadline, err := time.Parse(time.RFC3339, value); err == nil {
+		return deadline, true
+	}
+
+	if deadline, err := http.ParseTime(value); err == nil {
+		return deadline, true
+	}
+
+	return time.Time{}, false
+}
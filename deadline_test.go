@@ -0,0 +1,45 @@
+package httpc
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestResponseDeadline(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		opts   []ClientOption
+		values map[string]string
+		want   time.Time
+		ok     bool
+	}{
+		{"timestamp", nil, map[string]string{"X-Deadline": now.Format(time.RFC3339)}, now, true},
+		{"seconds from date", nil, map[string]string{"X-Deadline": "30", "Date": now.Format(http.TimeFormat)}, now.Add(30 * time.Second), true},
+		{"malformed", nil, map[string]string{"X-Deadline": "soon"}, time.Time{}, false},
+		{"absent", nil, nil, time.Time{}, false},
+		{"custom header", []ClientOption{WithDeadlineHeader("Valid-Until")}, map[string]string{"Valid-Until": now.Format(http.TimeFormat)}, now, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(context.Background(), &Config{BaseUrl: "http://localhost"}, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp := &http.Response{Header: http.Header{}}
+			for key, value := range tt.values {
+				resp.Header.Set(key, value)
+			}
+
+			deadline, ok := client.ResponseDeadline(resp)
+			if ok != tt.ok || !deadline.Equal(tt.want) {
+				t.Fatalf("expected (%s, %t), got (%s, %t)", tt.want, tt.ok, deadline, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package httpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Decoder decodes a response body into v, letting a client use a faster JSON implementation or another format entirely
+type Decoder interface {
+	Decode(r io.Reader, v interface{}) error
+}
+
+// JSONDecoder decodes response bodies with encoding/json
+type JSONDecoder struct{}
+
+// Decode implements the Decoder interface
+func (JSONDecoder) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// XMLDecoder decodes response bodies with encoding/xml
+type XMLDecoder struct{}
+
+// Decode implements the Decoder interface
+func (XMLDecoder) Decode(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// decoderFor returns the decoder registered for the media type of the response, falling back to the client's decoder. Structured
+// syntax suffixes such as application/atom+xml match the decoder registered for the base type, application/xml. Without a custom
+// decoder only JSON media types are decoded, so it reports false for any other type. Responses without a Content-Type, or with
+// text/plain which servers such as net/http fall back to for unlabelled bodies, are decoded with the default decoder
+func (c *Client) decoderFor(resp *http.Response) (Decoder, bool) {
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		return c.decoder, true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return c.decoder, c.decoder != nil
+	}
+
+	if decoder, ok := c.decoders[mediaType]; ok {
+		return decoder, true
+	}
+
+	suffix := ""
+	if i := strings.LastIndexByte(mediaType, '+'); i >= 0 {
+		suffix = mediaType[i+1:]
+
+		if decoder, ok := c.decoders["application/"+suffix]; ok {
+			return decoder, true
+		}
+	}
+
+	if c.decoder != nil {
+		return c.decoder, true
+	}
+
+	return nil, mediaType == "application/json" || suffix == "json" || mediaType == "text/plain"
+}
+
+// unexpectedContentType reads the start of a body that can't be decoded so the error shows what the server sent instead
+func unexpectedContentType(resp *http.Response, body io.Reader) *UnexpectedContentType {
+	snippet, _ := io.ReadAll(io.LimitReader(body, contentTypeSnippetSize))
+
+	return &UnexpectedContentType{resp.Header.Get("Content-Type"), snippet}
+}
+
+// unmarshal decodes an already read body with the supplied decoder, or encoding/json when it is nil
+func (c *Client) unmarshal(data []byte, decoder Decoder, decoded interface{}) error {
+	if decoder != nil {
+		return decoder.Decode(bytes.NewReader(data), decoded)
+	}
+
+	return json.Unmarshal(data, decoded)
+}
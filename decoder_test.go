@@ -0,0 +1,179 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// prefixDecoder decodes any body into a string with a fixed prefix
+type prefixDecoder struct {
+	calls int
+}
+
+func (d *prefixDecoder) Decode(r io.Reader, v interface{}) error {
+	d.calls++
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	*(v.(*string)) = "decoded:" + string(body)
+
+	return nil
+}
+
+func TestDecoder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("raw"))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		opts func(Decoder) []ClientOption
+	}{
+		{
+			name: "streamed",
+			opts: func(d Decoder) []ClientOption { return []ClientOption{WithDecoder(d)} },
+		},
+		{
+			name: "buffered",
+			opts: func(d Decoder) []ClientOption { return []ClientOption{WithDecoder(d), WithBufferedDecode()} },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := &prefixDecoder{}
+
+			client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, tt.opts(decoder)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out string
+
+			if _, err := client.Get(context.Background(), "/", nil, &out); err != nil {
+				t.Fatal(err)
+			}
+
+			if out != "decoded:raw" {
+				t.Fatalf("expected Get to use the custom decoder, got %q", out)
+			}
+
+			out = ""
+
+			if result := client.Request(context.Background(), RequestSpec{Method: http.MethodGet, Resource: "/", Decoded: &out}); result.Err != nil {
+				t.Fatal(result.Err)
+			}
+
+			if out != "decoded:raw" {
+				t.Fatalf("expected Request to use the custom decoder, got %q", out)
+			}
+
+			if decoder.calls != 2 {
+				t.Fatalf("expected 2 decoder calls, got %d", decoder.calls)
+			}
+		})
+	}
+}
+
+func TestXMLDecoder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/json" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"Name":"json"}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		w.Write([]byte(`<item><Name>xml</Name></item>`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithXMLDecoder())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, contentType := range []string{"application/xml; charset=utf-8", "application/atom+xml", "text/xml"} {
+		t.Run(contentType, func(t *testing.T) {
+			var out struct{ Name string }
+
+			if _, err := client.GetWithParams(context.Background(), "/xml", url.Values{"type": {contentType}}, nil, &out); err != nil {
+				t.Fatal(err)
+			}
+
+			if out.Name != "xml" {
+				t.Fatalf("expected the XML body to be decoded, got %q", out.Name)
+			}
+		})
+	}
+
+	var out struct{ Name string }
+
+	if _, err := client.Get(context.Background(), "/json", nil, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Name != "json" {
+		t.Fatalf("expected JSON responses to still decode as JSON, got %q", out.Name)
+	}
+}
+
+func TestContentTypeDispatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/vnd.api+json")
+			w.Write([]byte(`{"Name":"json"}`))
+		case "/html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<html>error page</html>`))
+		case "/none":
+			w.Header()["Content-Type"] = nil
+			w.Write([]byte(`{"Name":"none"}`))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, resource := range []string{"/json", "/none"} {
+		var out struct{ Name string }
+
+		if _, err := client.Get(context.Background(), resource, nil, &out); err != nil {
+			t.Fatalf("%s: %v", resource, err)
+		}
+
+		if out.Name != resource[1:] {
+			t.Fatalf("%s: expected the body to be decoded as JSON, got %q", resource, out.Name)
+		}
+	}
+
+	var out struct{ Name string }
+
+	_, err = client.Get(context.Background(), "/html", nil, &out)
+
+	var unexpected *UnexpectedContentType
+	if !errors.As(err, &unexpected) || !errors.Is(err, ErrUnexpectedContentType) {
+		t.Fatalf("expected *UnexpectedContentType, got %v", err)
+	}
+
+	if snippet := string(unexpected.Snippet()); snippet != "<html>error page</html>" {
+		t.Fatalf("expected the body snippet, got %q", snippet)
+	}
+
+	if result := client.Request(context.Background(), RequestSpec{Method: http.MethodGet, Resource: "/html", Decoded: &out}); !errors.Is(result.Err, ErrUnexpectedContentType) {
+		t.Fatalf("expected Request to reject the content type, got %v", result.Err)
+	}
+}
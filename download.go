@@ -0,0 +1,157 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// GetRange makes a GET request for the bytes from start to end inclusive of the supplied endpoint. A negative end requests every byte
+// from start onwards. The response is returned with its body open and the caller must close it. Servers that don't support ranges
+// respond with 200 OK and the full body rather than 206 Partial Content, so the status code should be checked before using the body
+func (c *Client) GetRange(ctx context.Context, resource string, start, end int64, headers map[string]string) (*http.Response, error) {
+	reqHeaders := make(map[string]string, len(headers)+1)
+	for key, val := range headers {
+		reqHeaders[key] = val
+	}
+
+	reqHeaders["Range"] = byteRange(start, end)
+
+	return c.open(ctx, http.MethodGet, resource, nil, reqHeaders)
+}
+
+// byteRange formats a Range header for the supplied bytes, leaving the range open ended when end is negative
+func byteRange(start, end int64) string {
+	if end < 0 {
+		return "bytes=" + strconv.FormatInt(start, 10) + "-"
+	}
+
+	return "bytes=" + strconv.FormatInt(start, 10) + "-" + strconv.FormatInt(end, 10)
+}
+
+// DownloadFile makes a GET request to the supplied endpoint and streams the response body to a temp file beside destPath, renaming
+// it into place with mode 0644 once the body is fully written and returning the number of bytes written. The temp file is removed on
+// any error, leaving destPath untouched. A body exceeding the maximum response size is returned as a ResponseTooLarge error and other
+// errors encountered while copying are returned as is
+func (c *Client) DownloadFile(ctx context.Context, resource string, destPath string, headers map[string]string) (int64, error) {
+	resp, err := c.open(ctx, http.MethodGet, resource, nil, headers)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	file, err := os.CreateTemp(filepath.Dir(destPath), "."+filepath.Base(destPath)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := writeFile(file, c.limitBody(resp.Body))
+	if err != nil {
+		os.Remove(file.Name())
+		return written, err
+	}
+
+	// temp files are created with mode 0600, the downloaded file gets the same mode as one written by ResumeDownload
+	if err := os.Chmod(file.Name(), 0o644); err != nil {
+		os.Remove(file.Name())
+		return written, err
+	}
+
+	if err := os.Rename(file.Name(), destPath); err != nil {
+		os.Remove(file.Name())
+		return written, err
+	}
+
+	return written, nil
+}
+
+// writeFile copies the body to the file and flushes it to disk before closing it
+func writeFile(file *os.File, body io.Reader) (int64, error) {
+	written, err := io.Copy(file, body)
+	if err == nil {
+		err = file.Sync()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return written, err
+}
+
+// ResumeDownload downloads the supplied endpoint like DownloadFile, writing to destPath with a .part suffix that is kept when the
+// download fails so the next call resumes with a Range request from the size of the partial file. The partial file is renamed into
+// place once the body is fully written. It starts over when the server ignores the range and treats 416 Range Not Satisfiable as the
+// partial file already being complete. It returns the number of bytes written by this call
+func (c *Client) ResumeDownload(ctx context.Context, resource string, destPath string, headers map[string]string) (int64, error) {
+	partPath := destPath + ".part"
+
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		file.Close()
+		return 0, err
+	}
+
+	reqHeaders := make(map[string]string, len(headers)+1)
+	for key, val := range headers {
+		reqHeaders[key] = val
+	}
+
+	if offset > 0 {
+		reqHeaders["Range"] = byteRange(offset, -1)
+	}
+
+	resp, err := c.open(ctx, http.MethodGet, resource, nil, reqHeaders)
+	if err != nil {
+		file.Close()
+
+		var badStatus *BadStatusCode
+		if offset > 0 && errors.As(err, &badStatus) && badStatus.StatusCode() == http.StatusRequestedRangeNotSatisfiable {
+			return 0, os.Rename(partPath, destPath)
+		}
+
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPartialContent {
+		if contentRange := resp.Header.Get("Content-Range"); !strings.HasPrefix(contentRange, "bytes "+strconv.FormatInt(offset, 10)+"-") {
+			file.Close()
+			return 0, &RequestError{errors.New("unexpected Content-Range for resumed download: " + contentRange)}
+		}
+	} else if offset > 0 {
+		// the server ignored the range and sent the full body
+		if err := file.Truncate(0); err != nil {
+			file.Close()
+			return 0, err
+		}
+
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			file.Close()
+			return 0, err
+		}
+	}
+
+	written, err := writeFile(file, c.limitBody(resp.Body))
+	if err != nil {
+		// a runaway body isn't worth resuming
+		var tooLarge *ResponseTooLarge
+		if errors.As(err, &tooLarge) {
+			os.Remove(partPath)
+		}
+
+		return written, err
+	}
+
+	return written, os.Rename(partPath, destPath)
+}
@@ -0,0 +1,227 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// truncate advertises a Content-Length of size but closes the connection after writing body
+func truncate(w http.ResponseWriter, body string, size string) {
+	w.Header().Set("Content-Length", size)
+	w.Write([]byte(body))
+	w.(http.Flusher).Flush()
+
+	if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+		conn.Close()
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte(strings.Repeat("a", 100)))
+		case "/truncated":
+			truncate(w, "partial", "1000")
+		case "/large":
+			w.Write([]byte(strings.Repeat("b", 5000)))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithMaxResponseBytes(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "ok")
+
+	n, err := client.DownloadFile(context.Background(), "/ok", dest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 100 {
+		t.Fatalf("expected 100 bytes written, got %d", n)
+	}
+
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != strings.Repeat("a", 100) {
+		t.Fatalf("unexpected file content %q", data)
+	}
+
+	info, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mode := info.Mode().Perm(); mode != 0o644 {
+		t.Fatalf("expected mode 0644, got %v", mode)
+	}
+
+	if _, err := client.DownloadFile(context.Background(), "/truncated", filepath.Join(dir, "truncated"), nil); err == nil {
+		t.Fatal("expected an error for a truncated body")
+	}
+
+	var tooLarge *ResponseTooLarge
+
+	if _, err := client.DownloadFile(context.Background(), "/large", filepath.Join(dir, "large"), nil); !errors.As(err, &tooLarge) {
+		t.Fatalf("expected *ResponseTooLarge, got %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expected failed downloads to leave no files behind, found %d entries", len(entries))
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.GetRange(context.Background(), "/file", 10, 19, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("expected status %d, got %d", http.StatusPartialContent, resp.StatusCode)
+	}
+
+	if string(body) != "0123456789" {
+		t.Fatalf("unexpected range body %q", body)
+	}
+}
+
+func TestResumeDownload(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+
+	var interrupt atomic.Bool
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/no-range":
+			w.Write([]byte(content))
+		case interrupt.Load():
+			truncate(w, content[:300], "1000")
+		default:
+			http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "out")
+
+	interrupt.Store(true)
+
+	if _, err := client.ResumeDownload(context.Background(), "/file", dest, nil); err == nil {
+		t.Fatal("expected an error for an interrupted download")
+	}
+
+	info, err := os.Stat(dest + ".part")
+	if err != nil {
+		t.Fatalf("expected the partial file to be kept: %v", err)
+	}
+
+	if info.Size() != 300 {
+		t.Fatalf("expected 300 partial bytes, got %d", info.Size())
+	}
+
+	interrupt.Store(false)
+
+	n, err := client.ResumeDownload(context.Background(), "/file", dest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 700 {
+		t.Fatalf("expected the remaining 700 bytes to be fetched, got %d", n)
+	}
+
+	assertFile(t, dest, content)
+
+	t.Run("range ignored", func(t *testing.T) {
+		if err := os.WriteFile(dest+".part", []byte("stale"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		n, err := client.ResumeDownload(context.Background(), "/no-range", dest, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != int64(len(content)) {
+			t.Fatalf("expected the download to restart, got %d bytes", n)
+		}
+
+		assertFile(t, dest, content)
+	})
+
+	t.Run("already complete", func(t *testing.T) {
+		if err := os.WriteFile(dest+".part", []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		n, err := client.ResumeDownload(context.Background(), "/file", dest, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != 0 {
+			t.Fatalf("expected no bytes to be fetched, got %d", n)
+		}
+
+		assertFile(t, dest, content)
+	})
+}
+
+func assertFile(t *testing.T, path string, want string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != want {
+		t.Fatalf("unexpected content in %s: got %d bytes", path, len(data))
+	}
+}
@@ -1,5 +1,28 @@
 package httpc
 
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Sentinel errors matched with errors.Is to branch on the category of an error without asserting its type
+var (
+	// ErrRateLimited matches a RateLimitError and a BadStatusCode for 429 Too Many Requests
+	ErrRateLimited = errors.New("rate limited")
+	// ErrBadStatus matches a BadStatusCode
+	ErrBadStatus = errors.New("bad status code")
+	// ErrDecode matches a DecodeError
+	ErrDecode = errors.New("decode failed")
+	// ErrRequest matches a RequestError
+	ErrRequest = errors.New("request failed")
+	// ErrUnexpectedContentType matches an UnexpectedContentType error
+	ErrUnexpectedContentType = errors.New("unexpected content type")
+)
+
 type InvalidResource struct {
 	err error
 }
@@ -8,6 +31,22 @@ func (e *InvalidResource) Error() string {
 	return "error parsing resource: " + e.err.Error()
 }
 
+func (e *InvalidResource) Unwrap() error {
+	return e.err
+}
+
+type InvalidRequest struct {
+	err error
+}
+
+func (e *InvalidRequest) Error() string {
+	return "request failed validation: " + e.err.Error()
+}
+
+func (e *InvalidRequest) Unwrap() error {
+	return e.err
+}
+
 type RequestError struct {
 	err error
 }
@@ -16,20 +55,173 @@ func (e *RequestError) Error() string {
 	return "error making HTTP request: " + e.err.Error()
 }
 
+func (e *RequestError) Unwrap() error {
+	return e.err
+}
+
+func (e *RequestError) Is(target error) bool {
+	return target == ErrRequest
+}
+
 type BadStatusCode struct {
-	msg string
+	msg    string
+	status string
+	code   int
+	header http.Header
+	body   []byte
+}
+
+// newBadStatusCode captures the status, headers and body of a non 2XX response
+func newBadStatusCode(resp *http.Response, pool *bufferPool) *BadStatusCode {
+	body, _ := pool.readAll(resp.Body)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	errBody := &bytes.Buffer{}
+	resp.Write(errBody)
+
+	return &BadStatusCode{
+		msg:    errBody.String(),
+		status: resp.Status,
+		code:   resp.StatusCode,
+		header: resp.Header.Clone(),
+		body:   body,
+	}
 }
 
 func (e *BadStatusCode) Error() string {
 	return "recieved bad status code: " + e.msg
 }
 
-type DecodeError struct {
+func (e *BadStatusCode) Is(target error) bool {
+	return target == ErrBadStatus || (target == ErrRateLimited && e.code == http.StatusTooManyRequests)
+}
+
+// StatusCode returns the status code of the response
+func (e *BadStatusCode) StatusCode() int {
+	return e.code
+}
+
+// Body returns the body of the response
+func (e *BadStatusCode) Body() []byte {
+	return e.body
+}
+
+// AsResponse reconstructs a response from the captured status, headers and body so it can be passed to existing response handling code
+func (e *BadStatusCode) AsResponse() *http.Response {
+	header := e.header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        e.status,
+		StatusCode:    e.code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+	}
+}
+
+type EncodeError struct {
 	err error
 }
 
+func (e *EncodeError) Error() string {
+	return "failed to encode request body: " + e.err.Error()
+}
+
+func (e *EncodeError) Unwrap() error {
+	return e.err
+}
+
+type DecodeError struct {
+	err     error
+	offset  int64
+	snippet []byte
+}
+
+// decodeSnippetRadius is the number of bytes captured on either side of the offset of a decode error
+const decodeSnippetRadius int64 = 32
+
+// newDecodeError captures the bytes surrounding the offset of the error within the buffered body when the decoder reports one
+func newDecodeError(err error, body []byte) *DecodeError {
+	var offset int64
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return &DecodeError{err: err}
+	}
+
+	start := max(offset-decodeSnippetRadius, 0)
+	end := min(offset+decodeSnippetRadius, int64(len(body)))
+
+	return &DecodeError{
+		err:     err,
+		offset:  offset,
+		snippet: bytes.Clone(body[start:end]),
+	}
+}
+
 func (e *DecodeError) Error() string {
-	return "failed to decode response body: " + e.err.Error()
+	if e.snippet == nil {
+		return "failed to decode response body: " + e.err.Error()
+	}
+
+	return "failed to decode response body: " + e.err.Error() + " near offset " + strconv.FormatInt(e.offset, 10) + ": " + strconv.Quote(string(e.snippet))
+}
+
+// Offset returns the byte offset of the error within the body, or zero when the body wasn't buffered
+func (e *DecodeError) Offset() int64 {
+	return e.offset
+}
+
+// Snippet returns the bytes surrounding the error offset, or nil when the body wasn't buffered
+func (e *DecodeError) Snippet() []byte {
+	return e.snippet
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.err
+}
+
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrDecode
+}
+
+// contentTypeSnippetSize is the number of bytes captured from the start of a body with an unexpected content type
+const contentTypeSnippetSize int64 = 64
+
+type UnexpectedContentType struct {
+	contentType string
+	snippet     []byte
+}
+
+func (e *UnexpectedContentType) Error() string {
+	return "unexpected response content type " + strconv.Quote(e.contentType) + ": " + strconv.Quote(string(e.snippet))
+}
+
+func (e *UnexpectedContentType) Is(target error) bool {
+	return target == ErrUnexpectedContentType
+}
+
+// ContentType returns the Content-Type header of the response
+func (e *UnexpectedContentType) ContentType() string {
+	return e.contentType
+}
+
+// Snippet returns the bytes at the start of the response body
+func (e *UnexpectedContentType) Snippet() []byte {
+	return e.snippet
 }
 
 type CopyError struct {
@@ -39,3 +231,123 @@ type CopyError struct {
 func (e *CopyError) Error() string {
 	return "failed to copy request body: " + e.err.Error()
 }
+
+func (e *CopyError) Unwrap() error {
+	return e.err
+}
+
+type CertificateError struct {
+	err error
+}
+
+func (e *CertificateError) Error() string {
+	return "failed to load client certificate: " + e.err.Error()
+}
+
+func (e *CertificateError) Unwrap() error {
+	return e.err
+}
+
+type DiscriminatorError struct {
+	msg string
+}
+
+func (e *DiscriminatorError) Error() string {
+	return "failed to match discriminator: " + e.msg
+}
+
+type BatchError struct {
+	msg string
+}
+
+func (e *BatchError) Error() string {
+	return "batch request failed: " + e.msg
+}
+
+type ConfigError struct {
+	msg string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid config: " + e.msg
+}
+
+type ResponseTooLarge struct {
+	limit int64
+	read  int64
+}
+
+func (e *ResponseTooLarge) Error() string {
+	return "response body exceeded limit of " + strconv.FormatInt(e.limit, 10) + " bytes after reading " + strconv.FormatInt(e.read, 10) + " bytes"
+}
+
+// Limit returns the configured maximum response size
+func (e *ResponseTooLarge) Limit() int64 {
+	return e.limit
+}
+
+// Read returns the number of bytes read before the limit was exceeded
+func (e *ResponseTooLarge) Read() int64 {
+	return e.read
+}
+
+type RetryBodyTooLarge struct {
+	limit int64
+	size  int64
+}
+
+func (e *RetryBodyTooLarge) Error() string {
+	return "request body exceeded retry buffer limit of " + strconv.FormatInt(e.limit, 10) + " bytes with at least " + strconv.FormatInt(e.size, 10) + " bytes"
+}
+
+// Limit returns the configured retry buffer limit
+func (e *RetryBodyTooLarge) Limit() int64 {
+	return e.limit
+}
+
+// Size returns the declared content length of the body, or the number of bytes read before the limit was exceeded when it is unknown
+func (e *RetryBodyTooLarge) Size() int64 {
+	return e.size
+}
+
+type MissingHeader struct {
+	name string
+}
+
+func (e *MissingHeader) Error() string {
+	return "response is missing required header: " + e.name
+}
+
+// Header returns the name of the missing header
+func (e *MissingHeader) Header() string {
+	return e.name
+}
+
+type CircuitOpen struct {
+	host string
+}
+
+func (e *CircuitOpen) Error() string {
+	return "circuit breaker open for host: " + e.host
+}
+
+// Host returns the host whose circuit is open
+func (e *CircuitOpen) Host() string {
+	return e.host
+}
+
+type RateLimitError struct {
+	err error
+}
+
+func (e *RateLimitError) Error() string {
+	return "error waiting on rate limiter: " + e.err.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.err
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
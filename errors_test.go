@@ -0,0 +1,234 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBadStatusCodeAsResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "abc")
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":"conflict"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Get(context.Background(), "/", nil, nil)
+
+	var statusErr *BadStatusCode
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected *BadStatusCode, got %v", err)
+	}
+
+	resp := statusErr.AsResponse()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusConflict || resp.Status != "409 Conflict" {
+		t.Fatalf("expected status 409 Conflict, got %d %q", resp.StatusCode, resp.Status)
+	}
+
+	if string(body) != `{"error":"conflict"}` {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	if resp.Header.Get("X-Request-Id") != "abc" {
+		t.Fatalf("expected captured headers, got %v", resp.Header)
+	}
+}
+
+func TestRequestErrorUnwrap(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	baseUrl := srv.URL
+	srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: baseUrl})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Get(context.Background(), "/", nil, nil)
+
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("expected *RequestError, got %v", err)
+	}
+
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("expected the wrapped *net.OpError, got %v", err)
+	}
+}
+
+func TestBadStatusCodeAccessors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":"version conflict"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Get(context.Background(), "/", nil, nil)
+
+	var statusErr *BadStatusCode
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected *BadStatusCode, got %v", err)
+	}
+
+	if statusErr.StatusCode() != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d", statusErr.StatusCode())
+	}
+
+	if !strings.Contains(string(statusErr.Body()), "version conflict") {
+		t.Fatalf("expected the body to contain the server's message, got %q", statusErr.Body())
+	}
+}
+
+func TestDecodeErrorSnippet(t *testing.T) {
+	body := `{"items":[` + strings.Repeat(`{"a":1},`, 100) + `{"a":1 "b"}]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	buffered, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithBufferedDecode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]any
+	_, err = buffered.Get(context.Background(), "/", nil, &decoded)
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected *DecodeError, got %v", err)
+	}
+
+	if !strings.Contains(string(decodeErr.Snippet()), `{"a":1 "b"}`) || !strings.Contains(err.Error(), "near offset") {
+		t.Fatalf("expected the error to include the malformed bytes, got %v", err)
+	}
+
+	streaming, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = streaming.Get(context.Background(), "/", nil, &decoded)
+	if !errors.As(err, &decodeErr) || decodeErr.Snippet() != nil {
+		t.Fatalf("expected no snippet without buffered decoding, got %v", err)
+	}
+
+	// Request buffers the body regardless, so its decode errors always carry a snippet
+	result := streaming.Request(context.Background(), RequestSpec{Method: http.MethodGet, Resource: "/", Decoded: &decoded})
+	if !errors.As(result.Err, &decodeErr) || decodeErr.Snippet() == nil {
+		t.Fatalf("expected a snippet from the buffered result, got %v", result.Err)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/limited":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/failed":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte("{bad"))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unreachable, err := NewClient(context.Background(), &Config{BaseUrl: "http://127.0.0.1:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limited, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RateLimit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// drain the only token so the next request has to wait
+	limited.Get(context.Background(), "/failed", nil, nil)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var out map[string]any
+
+	tests := []struct {
+		name  string
+		do    func() error
+		is    []error
+		isNot []error
+	}{
+		{
+			name: "too many requests",
+			do:   func() error { _, err := client.Get(context.Background(), "/limited", nil, nil); return err },
+			is:   []error{ErrRateLimited, ErrBadStatus},
+		},
+		{
+			name:  "server error",
+			do:    func() error { _, err := client.Get(context.Background(), "/failed", nil, nil); return err },
+			is:    []error{ErrBadStatus},
+			isNot: []error{ErrRateLimited},
+		},
+		{
+			name:  "decode",
+			do:    func() error { _, err := client.Get(context.Background(), "/json", nil, &out); return err },
+			is:    []error{ErrDecode},
+			isNot: []error{ErrRequest},
+		},
+		{
+			name: "transport",
+			do:   func() error { _, err := unreachable.Get(context.Background(), "/", nil, nil); return err },
+			is:   []error{ErrRequest},
+		},
+		{
+			name: "rate limit wait",
+			do:   func() error { _, err := limited.Get(canceled, "/failed", nil, nil); return err },
+			is:   []error{ErrRateLimited, context.Canceled},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.do()
+
+			for _, target := range tt.is {
+				if !errors.Is(err, target) {
+					t.Errorf("expected errors.Is(%v, %v)", err, target)
+				}
+			}
+
+			for _, target := range tt.isNot {
+				if errors.Is(err, target) {
+					t.Errorf("expected !errors.Is(%v, %v)", err, target)
+				}
+			}
+		})
+	}
+}
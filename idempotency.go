@@ -0,0 +1,61 @@
+package httpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+)
+
+const IdempotencyKeyHeader string = "Idempotency-Key"
+
+// idempotentKey marks a request context whose request may be retried regardless of its method
+type idempotentKey struct{}
+
+// PostIdempotent makes a POST request like Post with the supplied key in an Idempotency-Key header, generating a random UUID when the key
+// is empty. The request is retried like an idempotent request even when non idempotent retries are disabled, and every attempt carries
+// the same key so the server can deduplicate them
+func (c *Client) PostIdempotent(ctx context.Context, resource, idempotencyKey string, body io.Reader, headers map[string]string, decoded interface{}) (*http.Response, error) {
+	if idempotencyKey == "" {
+		key, err := newUUID()
+		if err != nil {
+			return nil, err
+		}
+
+		idempotencyKey = key
+	}
+
+	reqHeaders := make(map[string]string, len(headers)+1)
+	for key, val := range headers {
+		reqHeaders[key] = val
+	}
+
+	reqHeaders[IdempotencyKeyHeader] = idempotencyKey
+
+	return c.do(context.WithValue(ctx, idempotentKey{}, true), http.MethodPost, resource, body, reqHeaders, decoded)
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() (string, error) {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return "", err
+	}
+
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+
+	buf := make([]byte, 36)
+	hex.Encode(buf, uuid[:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], uuid[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], uuid[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], uuid[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], uuid[10:])
+
+	return string(buf), nil
+}
@@ -0,0 +1,81 @@
+package httpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPostIdempotent(t *testing.T) {
+	var mu sync.Mutex
+	var keys, bodies []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		bodies = append(bodies, string(body))
+
+		if len(keys)%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	reset := func() ([]string, []string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		gotKeys, gotBodies := keys, bodies
+		keys, bodies = nil, nil
+
+		return gotKeys, gotBodies
+	}
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RetryEnabled: true, RetryMax: 3, RetryMaxDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.PostIdempotent(context.Background(), "/", "", strings.NewReader("payload"), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	gotKeys, gotBodies := reset()
+
+	if len(gotKeys) != 3 {
+		t.Fatalf("expected the post to be retried until it succeeded, got %d attempts", len(gotKeys))
+	}
+
+	if gotKeys[0] == "" || gotKeys[0] != gotKeys[1] || gotKeys[1] != gotKeys[2] {
+		t.Fatalf("expected every attempt to carry the same generated key, got %v", gotKeys)
+	}
+
+	if gotBodies[2] != "payload" {
+		t.Fatalf("expected the body to be replayed on retry, got %q", gotBodies[2])
+	}
+
+	if _, err := client.PostIdempotent(context.Background(), "/", "order-1", strings.NewReader("payload"), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if gotKeys, _ = reset(); gotKeys[0] != "order-1" {
+		t.Fatalf("expected the supplied key to be sent, got %q", gotKeys[0])
+	}
+
+	if _, err := client.Post(context.Background(), "/", strings.NewReader("payload"), nil, nil); err == nil {
+		t.Fatal("expected the plain post to fail without retrying")
+	}
+
+	if gotKeys, _ = reset(); len(gotKeys) != 1 {
+		t.Fatalf("expected a plain post not to be retried, got %d attempts", len(gotKeys))
+	}
+}
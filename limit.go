@@ -0,0 +1,86 @@
+package httpc
+
+import (
+	"errors"
+	"io"
+)
+
+// limitedReader returns a ResponseTooLarge error once more than limit bytes have been read
+type limitedReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.read > l.limit {
+		return 0, &ResponseTooLarge{l.limit, l.read}
+	}
+
+	// read one byte past the limit to detect bodies that exceed it
+	if remaining := l.limit + 1 - l.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+
+	if l.read > l.limit {
+		return n - int(l.read-l.limit), &ResponseTooLarge{l.limit, l.read}
+	}
+
+	return n, err
+}
+
+// limitBody caps the supplied response body at the configured maximum response size
+func (c *Client) limitBody(body io.Reader) io.Reader {
+	if c.maxResponseBytes <= 0 {
+		return body
+	}
+
+	return &limitedReader{r: body, limit: c.maxResponseBytes}
+}
+
+// decode decodes the response body into decoded with the supplied decoder, or the pooled JSON decoder when it is nil. A ResponseTooLarge
+// error is returned as is instead of being wrapped in a DecodeError
+func (c *Client) decode(body io.Reader, decoder Decoder, decoded interface{}) error {
+	if c.bufferedDecode {
+		return c.decodeBuffered(body, decoder, decoded)
+	}
+
+	var err error
+	if decoder != nil {
+		err = decoder.Decode(c.limitBody(body), decoded)
+	} else {
+		err = c.bodyPool.decode(c.limitBody(body), decoded)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	var tooLarge *ResponseTooLarge
+	if errors.As(err, &tooLarge) {
+		return tooLarge
+	}
+
+	return &DecodeError{err: err}
+}
+
+// decodeBuffered reads the whole body before decoding it so a DecodeError can include the bytes surrounding the error
+func (c *Client) decodeBuffered(body io.Reader, decoder Decoder, decoded interface{}) error {
+	data, err := c.bodyPool.readAll(c.limitBody(body))
+	if err != nil {
+		if tooLarge, ok := err.(*ResponseTooLarge); ok {
+			return tooLarge
+		}
+
+		return &DecodeError{err: err}
+	}
+
+	if err := c.unmarshal(data, decoder, decoded); err != nil {
+		return newDecodeError(err, data)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package httpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseTooLarge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"` + strings.Repeat("a", 100) + `"}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		opts []ClientOption
+	}{
+		{"unpooled", []ClientOption{WithMaxResponseBytes(50)}},
+		{"pooled", []ClientOption{WithMaxResponseBytes(50), WithBodyBufferPool(16)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var tooLarge *ResponseTooLarge
+
+			var out struct{ Name string }
+			_, err = client.Get(context.Background(), "/", nil, &out)
+			if !errors.As(err, &tooLarge) || tooLarge.Limit() != 50 || tooLarge.Read() != 51 {
+				t.Fatalf("expected decode to fail with a limit of 50 after reading 51 bytes, got %v", err)
+			}
+
+			if errors.Unwrap(err) != nil {
+				t.Fatal("expected ResponseTooLarge not to wrap another error")
+			}
+
+			reader, err := client.Stream(context.Background(), http.MethodGet, "/", nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := io.ReadAll(reader)
+			if !errors.As(err, &tooLarge) || len(data) != 50 {
+				t.Fatalf("expected the stream to stop at 50 bytes, got %d bytes and %v", len(data), err)
+			}
+
+			var buf bytes.Buffer
+			written, err := client.StreamTo(context.Background(), http.MethodGet, "/", nil, nil, &buf)
+			if !errors.As(err, &tooLarge) || written != 50 {
+				t.Fatalf("expected StreamTo to stop at 50 bytes, got %d bytes and %v", written, err)
+			}
+
+			result := client.Request(context.Background(), RequestSpec{Method: http.MethodGet, Resource: "/"})
+			if !errors.As(result.Err, &tooLarge) {
+				t.Fatalf("expected the result to carry ResponseTooLarge, got %v", result.Err)
+			}
+		})
+	}
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithMaxResponseBytes(200))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct{ Name string }
+	if _, err := client.Get(context.Background(), "/", nil, &out); err != nil {
+		t.Fatalf("expected a body under the limit to decode, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package httpc
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// redactedHeaders are logged with their values replaced so credentials don't end up in logs
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "Api-Key"}
+
+// loggingTransport logs every attempt at debug level. It sits beneath the retry layer, so retries are logged as separate attempts
+type loggingTransport struct {
+	transport http.RoundTripper
+	logger    *slog.Logger
+
+	// redacted holds the canonical names of the headers logged with their values replaced
+	redacted []string
+}
+
+func newLoggingTransport(transport http.RoundTripper, logger *slog.Logger, extra []string) *loggingTransport {
+	redacted := append([]string(nil), redactedHeaders...)
+	for _, name := range extra {
+		redacted = append(redacted, http.CanonicalHeaderKey(name))
+	}
+
+	return &loggingTransport{transport, logger, redacted}
+}
+
+// RoundTrip implements the http.RoundTripper interface, logging the method, url, status code, duration and retry number of the attempt
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if !t.logger.Enabled(ctx, slog.LevelDebug) {
+		return t.transport.RoundTrip(req)
+	}
+
+	start := time.Now()
+	resp, err := t.transport.RoundTrip(req)
+
+	retry := RetryAttempt(req)
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("host", req.URL.Host),
+		slog.String("path", req.URL.Path),
+		slog.Duration("duration", time.Since(start)),
+		slog.Int("retry", retry),
+		slog.Any("headers", redactHeaders(req.Header, t.redacted)),
+	}
+
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	msg := "http request"
+	if retry > 0 {
+		msg = "http request retry"
+	}
+
+	t.logger.LogAttrs(ctx, slog.LevelDebug, msg, attrs...)
+
+	return resp, err
+}
+
+// redactHeaders returns a copy of the headers with the values of the named headers replaced
+func redactHeaders(header http.Header, names []string) http.Header {
+	redacted := header.Clone()
+
+	for _, name := range names {
+		if _, ok := redacted[name]; ok {
+			redacted[name] = []string{"REDACTED"}
+		}
+	}
+
+	return redacted
+}
@@ -0,0 +1,72 @@
+package httpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithLogger(t *testing.T) {
+	var attempts atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RetryEnabled: true, RetryMaxDelay: time.Millisecond}, WithLogger(logger), WithBearerToken("secret-token"), WithDefaultHeaders(map[string]string{"Cookie": "session=secret-cookie", "X-Api-Key": "secret-key", "X-Tenant-Token": "secret-tenant"}), WithRedactedHeaders("x-tenant-token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/path", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	output := buf.String()
+
+	for _, secret := range []string{"secret-token", "secret-cookie", "secret-key", "secret-tenant"} {
+		if strings.Contains(output, secret) {
+			t.Fatalf("expected %s to be redacted:\n%s", secret, output)
+		}
+	}
+
+	if !strings.Contains(output, "REDACTED") {
+		t.Fatalf("expected the redacted headers to be logged:\n%s", output)
+	}
+
+	var records []map[string]any
+
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+
+		records = append(records, record)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 log records, got %d:\n%s", len(records), output)
+	}
+
+	if records[0]["msg"] != "http request" || records[0]["status"] != float64(http.StatusServiceUnavailable) {
+		t.Fatalf("expected the failed attempt to be logged, got %v", records[0])
+	}
+
+	if records[1]["msg"] != "http request retry" || records[1]["retry"] != float64(1) || records[1]["path"] != "/path" {
+		t.Fatalf("expected the retry to be logged, got %v", records[1])
+	}
+}
@@ -0,0 +1,58 @@
+package httpc
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+// RequestMetrics holds the timings of a single request attempt
+type RequestMetrics struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Err        error
+
+	// TTFB is the duration from the start of the request to the first response byte. It is zero when no response was received
+	TTFB time.Duration
+
+	// Duration is the time taken for the response headers to be received
+	Duration time.Duration
+}
+
+// metricsTransport reports the timings of every request attempt to an observer
+type metricsTransport struct {
+	transport http.RoundTripper
+	observe   func(metrics RequestMetrics)
+}
+
+// RoundTrip implements the http.RoundTripper interface, measuring the time to first byte with an httptrace.ClientTrace
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var ttfb atomic.Int64
+
+	start := time.Now()
+	trace := &httptrace.ClientTrace{
+		GotFirstResponseByte: func() {
+			ttfb.Store(int64(time.Since(start)))
+		},
+	}
+
+	resp, err := t.transport.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+
+	metrics := RequestMetrics{
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Err:      err,
+		TTFB:     time.Duration(ttfb.Load()),
+		Duration: time.Since(start),
+	}
+
+	if resp != nil {
+		metrics.StatusCode = resp.StatusCode
+	}
+
+	t.observe(metrics)
+
+	return resp, err
+}
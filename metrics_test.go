@@ -0,0 +1,54 @@
+package httpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMetricsTimeToFirstByte(t *testing.T) {
+	const delay = 30 * time.Millisecond
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var observed []RequestMetrics
+
+	observer := func(metrics RequestMetrics) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		observed = append(observed, metrics)
+	}
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithMetricsObserver(observer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err == nil {
+		t.Fatal("expected an error for the 500 response")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(observed) != 1 {
+		t.Fatalf("expected 1 observation, got %d", len(observed))
+	}
+
+	if observed[0].StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", observed[0].StatusCode)
+	}
+
+	if observed[0].TTFB < delay {
+		t.Fatalf("expected a TTFB of at least %s, got %s", delay, observed[0].TTFB)
+	}
+}
@@ -0,0 +1,65 @@
+package httpc
+
+import (
+	"context"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// PostMultipart makes a multipart/form-data POST request to the supplied endpoint with the fields and files as form parts, in sorted
+// order of their names. Files are named after their form field unless the reader is an *os.File. The body is streamed as it is
+// written, so files aren't buffered in memory. If a struct pointer is supplied, the response body will be decoded into it
+func (c *Client) PostMultipart(ctx context.Context, resource string, fields map[string]string, files map[string]io.Reader, headers map[string]string, decoded interface{}) (*http.Response, error) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	// unblocks the writer when the request fails before the body is fully read
+	defer pr.Close()
+
+	reqHeaders := make(map[string]string, len(headers)+1)
+	for key, val := range headers {
+		reqHeaders[key] = val
+	}
+
+	reqHeaders["Content-Type"] = writer.FormDataContentType()
+
+	go func() {
+		pw.CloseWithError(writeMultipart(writer, fields, files))
+	}()
+
+	return c.do(ctx, http.MethodPost, resource, pr, reqHeaders, decoded)
+}
+
+// writeMultipart writes the fields followed by the files as form parts and closes the writer to write the trailing boundary
+func writeMultipart(writer *multipart.Writer, fields map[string]string, files map[string]io.Reader) error {
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		if err := writer.WriteField(name, fields[name]); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		file := files[name]
+
+		filename := name
+		if f, ok := file.(*os.File); ok {
+			filename = filepath.Base(f.Name())
+		}
+
+		part, err := writer.CreateFormFile(name, filename)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(part, file); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
@@ -0,0 +1,72 @@
+package httpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPostMultipart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		file, header, err := r.FormFile("upload")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		data, _ := io.ReadAll(file)
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"a":        r.FormValue("a"),
+			"b":        r.FormValue("b"),
+			"file":     string(data),
+			"filename": header.Filename,
+		})
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := map[string]string{"a": "1", "b": "2"}
+	files := map[string]io.Reader{"upload": strings.NewReader("hello")}
+
+	var echoed map[string]string
+	if _, err := client.PostMultipart(context.Background(), "/", fields, files, nil, &echoed); err != nil {
+		t.Fatal(err)
+	}
+
+	if echoed["a"] != "1" || echoed["b"] != "2" {
+		t.Fatalf("expected both fields to be received, got %v", echoed)
+	}
+
+	if echoed["file"] != "hello" || echoed["filename"] != "upload" {
+		t.Fatalf("expected the file part to be received, got %v", echoed)
+	}
+}
+
+func TestPostMultipartInvalidResource(t *testing.T) {
+	client, err := NewClient(context.Background(), &Config{BaseUrl: "http://localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the writer goroutine must not block forever on a body that is never sent
+	files := map[string]io.Reader{"upload": strings.NewReader(strings.Repeat("x", 1<<20))}
+
+	if _, err := client.PostMultipart(context.Background(), "::invalid", nil, files, nil, nil); err == nil {
+		t.Fatal("expected an invalid resource to fail before sending")
+	}
+}
@@ -0,0 +1,49 @@
+package httpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http"
+)
+
+// maxNDJSONLineSize is the longest line StreamNDJSON can decode
+const maxNDJSONLineSize int = 4 << 20
+
+// StreamNDJSON makes a GET request to the supplied endpoint and decodes each line of the newline delimited JSON body into the value
+// returned by newElem as it arrives, passing it to onElem. Blank lines are skipped and lines are decoded with the decoder set by
+// WithDecoder, or encoding/json by default. It stops on the first decode error, returned as a DecodeError, or error returned by onElem,
+// which is returned as is. Errors reading the stream, including the context error and lines longer than 4MB, are returned as is.
+// The client timeout doesn't apply, so long running streams such as log tails are bounded by the context alone
+func (c *Client) StreamNDJSON(ctx context.Context, resource string, headers map[string]string, newElem func() interface{}, onElem func(elem interface{}) error) error {
+	ctx = streamContext(ctx)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	body, err := c.Stream(ctx, http.MethodGet, resource, nil, headers)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLineSize)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		elem := newElem()
+		if err := c.unmarshal(line, c.decoder, elem); err != nil {
+			return newDecodeError(err, line)
+		}
+
+		if err := onElem(elem); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,86 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type ndjsonRecord struct {
+	N int
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(w, "{\"n\":%d}\n\n", i)
+			w.(http.Flusher).Flush()
+		}
+
+		if r.URL.Path == "/bad" {
+			w.Write([]byte("{oops\n"))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newElem := func() interface{} { return &ndjsonRecord{} }
+
+	var received []int
+	err = client.StreamNDJSON(context.Background(), "/", nil, newElem, func(elem interface{}) error {
+		received = append(received, elem.(*ndjsonRecord).N)
+		return nil
+	})
+	if err != nil || fmt.Sprint(received) != "[0 1 2 3 4]" {
+		t.Fatalf("expected every record in order, got %v, %v", received, err)
+	}
+
+	var decodeErr *DecodeError
+	err = client.StreamNDJSON(context.Background(), "/bad", nil, newElem, func(elem interface{}) error { return nil })
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected a DecodeError for a malformed line, got %v", err)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = client.StreamNDJSON(context.Background(), "/", nil, newElem, func(elem interface{}) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Fatalf("expected the callback error to stop the stream, got %v after %d calls", err, calls)
+	}
+}
+
+func TestStreamNDJSONOutlivesTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, "{\"n\":%d}\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, Timeout: int(50 * time.Millisecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := 0
+	err = client.StreamNDJSON(context.Background(), "/", nil, func() interface{} { return &ndjsonRecord{} }, func(elem interface{}) error {
+		received++
+		return nil
+	})
+	if err != nil || received != 3 {
+		t.Fatalf("expected the stream to outlive the client timeout, got %d records, %v", received, err)
+	}
+}
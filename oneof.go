@@ -0,0 +1,84 @@
+package httpc
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// OneOf decodes a response body into one of several targets, selected by the value of a discriminator field.
+// Pass a *OneOf as the decode target of any method that accepts one
+type OneOf struct {
+	// Key is the name of a top level discriminator field, or a JSON pointer (e.g. "/meta/type") to a nested one
+	Key string
+
+	// Targets maps each discriminator value to the pointer its body should be decoded into
+	Targets map[string]interface{}
+
+	// Matched holds the discriminator value of the decoded body
+	Matched string
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface by peeking the discriminator and decoding into the matching target
+func (o *OneOf) UnmarshalJSON(data []byte) error {
+	value, err := discriminator(data, o.Key)
+	if err != nil {
+		return err
+	}
+
+	target, ok := o.Targets[value]
+	if !ok {
+		return &DiscriminatorError{"no target for " + o.Key + " value " + strconv.Quote(value)}
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		return err
+	}
+
+	o.Matched = value
+
+	return nil
+}
+
+// discriminator looks up the value at the supplied key or JSON pointer. String values are unquoted, other values are returned as raw JSON
+func discriminator(data []byte, key string) (string, error) {
+	segments := []string{key}
+	if strings.HasPrefix(key, "/") {
+		segments = strings.Split(key[1:], "/")
+	}
+
+	raw := json.RawMessage(data)
+	for _, segment := range segments {
+		segment = strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err == nil {
+			next, ok := object[segment]
+			if !ok {
+				return "", &DiscriminatorError{"missing field " + key}
+			}
+
+			raw = next
+			continue
+		}
+
+		var array []json.RawMessage
+		if err := json.Unmarshal(raw, &array); err != nil {
+			return "", &DiscriminatorError{"cannot traverse " + key}
+		}
+
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index >= len(array) {
+			return "", &DiscriminatorError{"missing field " + key}
+		}
+
+		raw = array[index]
+	}
+
+	var value string
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return value, nil
+	}
+
+	return string(raw), nil
+}
@@ -0,0 +1,57 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOneOf(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cat":
+			w.Write([]byte(`{"meta":{"kind":"cat"},"lives":9}`))
+		default:
+			w.Write([]byte(`{"meta":{"kind":"dog"},"bark":"woof"}`))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type Cat struct{ Lives int }
+	type Dog struct{ Bark string }
+
+	var cat Cat
+	var dog Dog
+
+	decoded := &OneOf{Key: "/meta/kind", Targets: map[string]interface{}{"cat": &cat, "dog": &dog}}
+	if _, err := client.Get(context.Background(), "/cat", nil, decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Matched != "cat" || cat.Lives != 9 {
+		t.Fatalf("expected the cat shape, got %q %+v", decoded.Matched, cat)
+	}
+
+	if _, err := client.Get(context.Background(), "/dog", nil, decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Matched != "dog" || dog.Bark != "woof" {
+		t.Fatalf("expected the dog shape, got %q %+v", decoded.Matched, dog)
+	}
+
+	missing := &OneOf{Key: "kind", Targets: map[string]interface{}{}}
+	_, err = client.Get(context.Background(), "/cat", nil, missing)
+
+	var discErr *DiscriminatorError
+	if !errors.As(err, &discErr) {
+		t.Fatalf("expected *DiscriminatorError, got %v", err)
+	}
+}
@@ -2,18 +2,32 @@ package httpc
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"log/slog"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/throttled/throttled/v2"
 	"github.com/throttled/throttled/v2/store/memstore"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
 type ClientOption func(c *Client) error
 
-// WithCustomClient replaces the default http client with the supplied one
+// WithCustomClient replaces the default http client with the supplied one. The layers enabled by the config, such as retries and tracing, wrap a
+// copy of the client so its transport is still used. The dial timeout, proxy and client certificate options are applied to a clone of its
+// transport, failing with a ConfigError unless it is an *http.Transport
 func WithCustomClient(client *http.Client) ClientOption {
 	return func(c *Client) error {
 		c.Http = client
@@ -21,31 +35,101 @@ func WithCustomClient(client *http.Client) ClientOption {
 	}
 }
 
-// WithDefaultHeaders adds default headers to the client
+// WithDefaultHeaders adds default headers to the client, merging them with any default headers already set
 func WithDefaultHeaders(headers map[string]string) ClientOption {
 	return func(c *Client) error {
-		c.Headers = headers
+		for key, val := range headers {
+			c.setDefaultHeader(key, val)
+		}
+
+		return nil
+	}
+}
+
+// WithBearerToken sends the supplied token in a default Authorization header. Per request headers still take precedence
+func WithBearerToken(token string) ClientOption {
+	return func(c *Client) error {
+		c.setDefaultHeader("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// WithBasicAuth sends the supplied credentials in a default Authorization header using HTTP Basic authentication. Per request headers still take precedence
+func WithBasicAuth(username, password string) ClientOption {
+	return func(c *Client) error {
+		c.setDefaultHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent default header in place of DefaultUserAgent. Per request headers still take precedence
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *Client) error {
+		c.setDefaultHeader("User-Agent", userAgent)
 		return nil
 	}
 }
 
-// WithCredentials sets up oauth2 and replaces the default http client
-func WithCredentials(ctx context.Context, clientId, key, tokenUrl string) ClientOption {
+// setDefaultHeader adds a default header without discarding the others
+func (c *Client) setDefaultHeader(key, val string) {
+	if c.Headers == nil {
+		c.Headers = make(map[string]string)
+	}
+
+	c.Headers[key] = val
+}
+
+// hasDefaultHeader reports whether a default header is set for the key regardless of its case
+func (c *Client) hasDefaultHeader(key string) bool {
+	for name := range c.Headers {
+		if http.CanonicalHeaderKey(name) == http.CanonicalHeaderKey(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithCredentials sets up oauth2 and wraps the http client once all other options have been applied. The supplied scopes are requested
+// with every token
+func WithCredentials(ctx context.Context, clientId, key, tokenUrl string, scopes ...string) ClientOption {
 	return func(c *Client) error {
 		authUrl, err := url.ParseRequestURI(tokenUrl)
 		if err != nil {
 			return err
 		}
 
-		credentials := &clientcredentials.Config{
+		c.Credentials = &clientcredentials.Config{
 			ClientID:     clientId,
 			ClientSecret: key,
 			TokenURL:     authUrl.String(),
+			Scopes:       scopes,
 		}
 
-		ctx = context.WithValue(ctx, oauth2.HTTPClient, c.Http)
-		c.Http = credentials.Client(ctx)
+		c.authenticate = func(client *http.Client) *http.Client {
+			c.Credentials.AuthStyle = c.authStyle
+			c.Credentials.EndpointParams = c.endpointParams
+
+			return c.Credentials.Client(context.WithValue(ctx, oauth2.HTTPClient, client))
+		}
+
+		return nil
+	}
+}
+
+// WithAuthStyle sets how the client credentials are sent to the token endpoint, either in the request body or the Authorization header.
+// The default auto detects the style, which can cost an extra round trip
+func WithAuthStyle(style oauth2.AuthStyle) ClientOption {
+	return func(c *Client) error {
+		c.authStyle = style
+		return nil
+	}
+}
 
+// WithClientCredentialsEndpointParams adds the supplied params, such as audience or resource, to every token request made for WithCredentials
+func WithClientCredentialsEndpointParams(params url.Values) ClientOption {
+	return func(c *Client) error {
+		c.endpointParams = params
 		return nil
 	}
 }
@@ -53,22 +137,548 @@ func WithCredentials(ctx context.Context, clientId, key, tokenUrl string) Client
 // WithRateLimiter configures a rate limiter with the supplied limit (per minute)
 func WithRateLimiter(rateLimit int) ClientOption {
 	return func(c *Client) error {
-		store, err := memstore.NewCtx(MaxRateLimitKeys)
+		c.rateLimit = rateLimit
+		c.rateLimitStore = nil
+		return nil
+	}
+}
+
+// WithRateLimiterStore configures the rate limiter like WithRateLimiter with the supplied store in place of the in-memory store, such as a
+// redis backed store so the limit is shared by every instance of a service. It replaces the limiter configured by Config.RateLimit
+// and is shared by the limiters of WithRateLimitPerMethod
+func WithRateLimiterStore(store throttled.GCRAStoreCtx, rateLimit int) ClientOption {
+	return func(c *Client) error {
+		c.rateLimit = rateLimit
+		c.rateLimitStore = store
+		return nil
+	}
+}
+
+// WithRateLimitPerMethod configures a separate rate limiter for each supplied method with its limit (per minute). Requests with
+// other methods fall back to the limiter configured with WithRateLimiter
+func WithRateLimitPerMethod(limits map[string]int) ClientOption {
+	return func(c *Client) error {
+		c.methodLimits = limits
+		return nil
+	}
+}
+
+// WithRateLimitBurst sets the number of requests, or total request cost, every rate limiter allows at once before its rate applies.
+// It defaults to 1, spacing requests evenly, and caps the cost of a single PostWithCost request, so costs above 1 need a larger burst
+func WithRateLimitBurst(burst int) ClientOption {
+	return func(c *Client) error {
+		if burst < 1 {
+			return &ConfigError{"rate limit burst must be at least 1, got " + strconv.Itoa(burst)}
+		}
+
+		c.rateLimitBurst = burst
+
+		return nil
+	}
+}
+
+// WithRateLimitKeyFunc computes the rate limiter key for each request from its method and resource, so endpoints can be throttled
+// independently. Requests are keyed on the base url by default, or the request host for DoRequest to other hosts, which passes the request uri as the resource
+func WithRateLimitKeyFunc(fn func(method, resource string) string) ClientOption {
+	return func(c *Client) error {
+		c.rateLimitKey = fn
+		return nil
+	}
+}
+
+// buildRateLimiters creates the rate limiters configured by the options once they have all been applied, so the burst applies regardless of order
+func (c *Client) buildRateLimiters() error {
+	if c.rateLimit > 0 || c.rateLimitStore != nil {
+		store := c.rateLimitStore
+		if store == nil {
+			memStore, err := memstore.NewCtx(MaxRateLimitKeys)
+			if err != nil {
+				return err
+			}
+
+			store = memStore
+		}
+
+		rateLimiter, err := newRateLimiterWithStore(store, c.rateLimit, c.rateLimitBurst)
 		if err != nil {
 			return err
 		}
 
-		quota := throttled.RateQuota{
-			MaxRate: throttled.PerMin(rateLimit),
+		c.RateLimiter = rateLimiter
+	}
+
+	if c.methodLimits == nil {
+		return nil
+	}
+
+	// a supplied store is shared with the main limiter, the method limiters prefix their keys with the method so they don't collide
+	c.methodLimiters = make(map[string]*throttled.GCRARateLimiterCtx, len(c.methodLimits))
+	for method, rateLimit := range c.methodLimits {
+		var rateLimiter *throttled.GCRARateLimiterCtx
+		var err error
+
+		if c.rateLimitStore != nil {
+			rateLimiter, err = newRateLimiterWithStore(c.rateLimitStore, rateLimit, c.rateLimitBurst)
+		} else {
+			rateLimiter, err = newRateLimiter(rateLimit, c.rateLimitBurst)
 		}
 
-		rateLimiter, err := throttled.NewGCRARateLimiterCtx(store, quota)
 		if err != nil {
 			return err
 		}
 
-		c.RateLimiter = rateLimiter
+		c.methodLimiters[strings.ToUpper(method)] = rateLimiter
+	}
+
+	return nil
+}
+
+// newRateLimiter creates a GCRA rate limiter backed by an in memory store with the supplied limit (per minute)
+func newRateLimiter(rateLimit int, burst int) (*throttled.GCRARateLimiterCtx, error) {
+	store, err := memstore.NewCtx(MaxRateLimitKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRateLimiterWithStore(store, rateLimit, burst)
+}
+
+// newRateLimiterWithStore creates a GCRA rate limiter allowing burst requests at once, or a single request when burst isn't positive
+func newRateLimiterWithStore(store throttled.GCRAStoreCtx, rateLimit int, burst int) (*throttled.GCRARateLimiterCtx, error) {
+	// throttled counts the burst on top of the first request
+	quota := throttled.RateQuota{
+		MaxRate:  throttled.PerMin(rateLimit),
+		MaxBurst: max(burst-1, 0),
+	}
+
+	return throttled.NewGCRARateLimiterCtx(store, quota)
+}
+
+// WithAcceptGzip takes control of response compression away from the default transport. When enabled, requests are sent
+// with an Accept-Encoding: gzip header and gzip encoded responses are decompressed by the client. When disabled, responses are
+// requested uncompressed
+func WithAcceptGzip(enabled bool) ClientOption {
+	return func(c *Client) error {
+		c.disableCompression = true
+		c.acceptGzip = enabled
+		return nil
+	}
+}
+
+// WithDecompression decodes gzip and deflate encoded responses that the transport didn't decompress itself, such as when an Accept-Encoding
+// header is supplied with WithDefaultHeaders. Every encoding listed in the Content-Encoding header is decoded before the body is read
+func WithDecompression() ClientOption {
+	return func(c *Client) error {
+		c.decompress = true
+		return nil
+	}
+}
+
+// WithMaxRequestsPerConnection closes a connection once it has served n requests, forcing a new connection to be dialed.
+// This helps rebalance traffic behind load balancers that pin long lived connections
+func WithMaxRequestsPerConnection(n int) ClientOption {
+	return func(c *Client) error {
+		c.maxRequestsPerConn = n
+		return nil
+	}
+}
+
+// WithBodyBufferPool reads response bodies of up to size bytes into reusable buffers, reducing allocations for small responses
+func WithBodyBufferPool(size int) ClientOption {
+	return func(c *Client) error {
+		c.bodyPool = newBufferPool(size)
+		return nil
+	}
+}
+
+// WithOTelSpanNameFormatter names the span of every request with fn, which is passed the default operation name and the request.
+// It has no effect unless OTel is enabled
+func WithOTelSpanNameFormatter(fn func(operation string, req *http.Request) string) ClientOption {
+	return func(c *Client) error {
+		c.spanNameFormatter = fn
+		return nil
+	}
+}
+
+// WithOTelAttributes adds the supplied static attributes, such as the API version, to the span of every request. It has no effect unless OTel is enabled
+func WithOTelAttributes(attrs ...attribute.KeyValue) ClientOption {
+	return func(c *Client) error {
+		c.otelAttributes = append(c.otelAttributes, attrs...)
+		return nil
+	}
+}
+
+// WithPropagator injects the trace context and baggage of each request context into its headers with the supplied propagator, such as
+// propagation.TraceContext, in place of the W3C trace context and baggage propagators used when OTel is enabled. Unlike the other OTel
+// options it also applies when OTel is disabled, so trace context started by the caller still reaches the upstream service without the
+// client creating spans
+func WithPropagator(propagator propagation.TextMapPropagator) ClientOption {
+	return func(c *Client) error {
+		c.propagator = propagator
+		return nil
+	}
+}
+
+// WithSpanAttributesFromContext adds the attributes returned by fn to the span of every request. It is invoked with the request context
+// and has no effect unless OTel is enabled
+func WithSpanAttributesFromContext(fn func(ctx context.Context) []attribute.KeyValue) ClientOption {
+	return func(c *Client) error {
+		c.spanAttributes = fn
+		return nil
+	}
+}
+
+// WithResponseCache caches up to maxEntries GET responses in memory, keyed by url and the request headers named in their Vary header.
+// Responses are cached according to their Cache-Control and Expires headers, no-store and private responses are never cached and stale
+// responses with an ETag or Last-Modified header are revalidated with a conditional request. Only bodies with a known length up to the
+// maximum response size, or DefaultCacheMaxBodyBytes, are cached and streamed or downloaded responses bypass the cache
+func WithResponseCache(maxEntries int) ClientOption {
+	return func(c *Client) error {
+		c.cacheEntries = maxEntries
+		return nil
+	}
+}
+
+// WithPathNormalization cleans resolved request paths, collapsing duplicate slashes and resolving dot segments
+func WithPathNormalization() ClientOption {
+	return func(c *Client) error {
+		c.normalizePaths = true
+		return nil
+	}
+}
+
+// WithMetricsObserver calls fn with the timings of every request attempt, including the time to first byte. Attempts that fail with a bad
+// status code or a transport error are reported as well
+func WithMetricsObserver(fn func(metrics RequestMetrics)) ClientOption {
+	return func(c *Client) error {
+		c.observeMetrics = fn
+		return nil
+	}
+}
+
+// WithMaxResponseBytes caps the size of successful response bodies read by the client. Reading past the limit fails with a ResponseTooLarge error
+func WithMaxResponseBytes(limit int64) ClientOption {
+	return func(c *Client) error {
+		c.maxResponseBytes = limit
+		return nil
+	}
+}
+
+// WithDialTimeout sets the timeout for establishing connections, independent of the overall client timeout
+func WithDialTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) error {
+		c.dialTimeout = timeout
+		return nil
+	}
+}
+
+// WithURLRewriter calls fn with the fully resolved url of every request made through the verb methods, allowing it to be inspected or replaced
+// before the request is created. Returning an error aborts the request with an InvalidResource error
+func WithURLRewriter(fn func(u *url.URL) (*url.URL, error)) ClientOption {
+	return func(c *Client) error {
+		c.rewriteUrl = fn
+		return nil
+	}
+}
 
+// WithDeadlineHeader sets the response header read by ResponseDeadline. It is read only and doesn't affect the request
+func WithDeadlineHeader(name string) ClientOption {
+	return func(c *Client) error {
+		c.deadlineHeader = name
+		return nil
+	}
+}
+
+// WithMaxConcurrentPerHost caps the number of in flight requests to each host, so one slow host can't hold every request. A request
+// holds its permit until its response body is closed
+func WithMaxConcurrentPerHost(n int) ClientOption {
+	return func(c *Client) error {
+		c.maxConcurrentPerHost = n
+		return nil
+	}
+}
+
+// WithMaxConcurrentRequests caps the number of requests the client has in flight at once across every host, independent of the rate
+// limit and connection pool. Requests wait for a permit until their context is done and hold it until the response body is closed
+func WithMaxConcurrentRequests(n int) ClientOption {
+	return func(c *Client) error {
+		if n < 1 {
+			return &ConfigError{"max concurrent requests must be at least 1, got " + strconv.Itoa(n)}
+		}
+
+		c.requestPermits = make(chan struct{}, n)
+
+		return nil
+	}
+}
+
+// WithBufferedDecode reads response bodies in full before decoding them, so a DecodeError includes the offset of the error and the bytes around it
+func WithBufferedDecode() ClientOption {
+	return func(c *Client) error {
+		c.bufferedDecode = true
+		return nil
+	}
+}
+
+// WithResponseBodyReplay buffers successful response bodies, up to the maximum response size, so they can be read again after the
+// call with ReplayBody. This trades memory for convenience and is mostly useful in tests
+func WithResponseBodyReplay() ClientOption {
+	return func(c *Client) error {
+		c.replayBodies = true
+		return nil
+	}
+}
+
+// WithProxyFunc sets the proxy used by the default transport for each request. Returning a nil url sends the request directly
+func WithProxyFunc(fn func(req *http.Request) (*url.URL, error)) ClientOption {
+	return func(c *Client) error {
+		c.proxy = fn
+		return nil
+	}
+}
+
+// WithClientCert loads the certificate and key pair used for mutual TLS along with the CA bundle used to verify the server, setting
+// them on the default transport's TLS config. An empty caFile keeps the roots of Config.TlsConfig, or the system roots when it is nil.
+// Files that fail to load or parse are returned as a CertificateError
+func WithClientCert(certFile, keyFile, caFile string) ClientOption {
+	return func(c *Client) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return &CertificateError{err}
+		}
+
+		c.clientCert = &cert
+
+		if caFile == "" {
+			return nil
+		}
+
+		caPem, err := os.ReadFile(caFile)
+		if err != nil {
+			return &CertificateError{err}
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPem) {
+			return &CertificateError{errors.New("no certificates found in CA file: " + caFile)}
+		}
+
+		c.rootCAs = pool
+
+		return nil
+	}
+}
+
+// WithBaseURLPath joins the supplied prefix, such as /api/v2, in front of the path of every resource, which would otherwise replace the
+// path of the base url. Leading and trailing slashes are optional, resources that already start with the prefix are left as is and
+// absolute resource urls aren't prefixed
+func WithBaseURLPath(prefix string) ClientOption {
+	return func(c *Client) error {
+		if trimmed := strings.Trim(prefix, "/"); trimmed != "" {
+			c.basePath = "/" + trimmed
+		}
+
+		return nil
+	}
+}
+
+// WithProxy routes every request made by the default transport through the proxy at the supplied url
+func WithProxy(proxyUrl string) ClientOption {
+	return func(c *Client) error {
+		parsed, err := url.Parse(proxyUrl)
+		if err != nil {
+			return err
+		}
+
+		if parsed.Host == "" {
+			return &ConfigError{"proxy url is missing a host: " + proxyUrl}
+		}
+
+		c.proxy = http.ProxyURL(parsed)
+
+		return nil
+	}
+}
+
+// WithProxyFromEnvironment routes requests made by the default transport through the proxy set by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables
+func WithProxyFromEnvironment() ClientOption {
+	return func(c *Client) error {
+		c.proxy = http.ProxyFromEnvironment
+		return nil
+	}
+}
+
+// WithProxyForHosts routes requests to the listed hosts through their proxy url, sending every other request directly. Hosts are
+// matched against the request host with or without its port
+func WithProxyForHosts(proxies map[string]string) ClientOption {
+	return func(c *Client) error {
+		proxyUrls := make(map[string]*url.URL, len(proxies))
+		for host, proxy := range proxies {
+			proxyUrl, err := url.Parse(proxy)
+			if err != nil {
+				return err
+			}
+
+			proxyUrls[strings.ToLower(host)] = proxyUrl
+		}
+
+		c.proxy = func(req *http.Request) (*url.URL, error) {
+			if proxyUrl, ok := proxyUrls[strings.ToLower(req.URL.Host)]; ok {
+				return proxyUrl, nil
+			}
+
+			return proxyUrls[strings.ToLower(req.URL.Hostname())], nil
+		}
+
+		return nil
+	}
+}
+
+// WithRequiredResponseHeaders fails successful responses that are missing any of the named headers with a MissingHeader error
+// before their body is decoded or streamed
+func WithRequiredResponseHeaders(names ...string) ClientOption {
+	return func(c *Client) error {
+		c.requiredHeaders = append(c.requiredHeaders, names...)
+		return nil
+	}
+}
+
+// WithTransportMiddleware wraps the transport with the supplied middleware, the first being the outermost. Middleware sits beneath the
+// retry layer, so it sees every attempt, and inside the tracing span when OTel is enabled. It also wraps the transport of a custom client
+func WithTransportMiddleware(mw ...func(http.RoundTripper) http.RoundTripper) ClientOption {
+	return func(c *Client) error {
+		c.middleware = append(c.middleware, mw...)
+		return nil
+	}
+}
+
+// WithRequestHook calls fn with every request just before it is sent, allowing headers such as correlation ids to be set. It runs once
+// per call rather than once per retry attempt
+func WithRequestHook(fn func(req *http.Request)) ClientOption {
+	return func(c *Client) error {
+		c.requestHook = fn
+		return nil
+	}
+}
+
+// WithResponseHook calls fn with the response or error of every request as soon as it returns, including responses that fail the status
+// check. The response must not be modified and its body must not be read. It runs once per call rather than once per retry attempt
+func WithResponseHook(fn func(resp *http.Response, err error)) ClientOption {
+	return func(c *Client) error {
+		c.responseHook = fn
+		return nil
+	}
+}
+
+// WithHealthPath sets the path requested by Ping, resolved against the base url. The base url itself is requested by default
+func WithHealthPath(path string) ClientOption {
+	return func(c *Client) error {
+		c.healthPath = path
+		return nil
+	}
+}
+
+// WithCircuitBreaker fast fails requests to a host with a CircuitOpen error once failureThreshold consecutive attempts have failed with
+// a transport error or 5XX status code. After resetTimeout a single probe request is let through, closing the circuit when it succeeds.
+// The breaker sits beneath the retry layer, so an open circuit stops any further retries
+func WithCircuitBreaker(failureThreshold int, resetTimeout time.Duration) ClientOption {
+	return func(c *Client) error {
+		c.breakerThreshold = failureThreshold
+		c.breakerReset = resetTimeout
+		return nil
+	}
+}
+
+// WithCaptureRedirects records each redirect followed on the way to the final response so its status code and Location can be
+// inspected with Redirects. Redirects are still followed under the http.Client's own redirect policy
+func WithCaptureRedirects() ClientOption {
+	return func(c *Client) error {
+		c.captureRedirects = true
+		return nil
+	}
+}
+
+// WithRequestValidator checks every request once it is built with the default and supplied headers applied, before it is sent.
+// A validator error is returned as an InvalidRequest error without sending the request
+func WithRequestValidator(validate func(req *http.Request) error) ClientOption {
+	return func(c *Client) error {
+		c.validateRequest = validate
+		return nil
+	}
+}
+
+// WithCookieJar stores cookies set by responses in the supplied jar and sends them on subsequent requests. The jar is installed on the
+// client that ends up making requests regardless of option order, so it applies with WithCustomClient and WithCredentials and takes
+// precedence over a custom client's own jar
+func WithCookieJar(jar http.CookieJar) ClientOption {
+	return func(c *Client) error {
+		c.jar = jar
+		return nil
+	}
+}
+
+// WithDefaultCookieJar installs an in-memory cookie jar as with WithCookieJar
+func WithDefaultCookieJar() ClientOption {
+	return func(c *Client) error {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return err
+		}
+
+		c.jar = jar
+
+		return nil
+	}
+}
+
+// WithDecoder decodes response bodies with the supplied decoder in place of encoding/json. Pooled decoding through WithBodyBufferPool only
+// applies to the default decoder
+func WithDecoder(decoder Decoder) ClientOption {
+	return func(c *Client) error {
+		c.decoder = decoder
+		return nil
+	}
+}
+
+// WithXMLDecoder decodes responses with an application/xml or text/xml Content-Type, or an +xml suffix, with encoding/xml. Responses
+// of any other type are still decoded with the default decoder
+func WithXMLDecoder() ClientOption {
+	return WithContentTypeDecoder(XMLDecoder{}, "application/xml", "text/xml")
+}
+
+// WithContentTypeDecoder decodes responses whose Content-Type matches one of the supplied media types with the decoder. Registering
+// application/<suffix> also matches media types with that structured syntax suffix, such as application/vnd.api+json for application/json.
+// Without a decoder for the media type, responses are decoded by the decoder set with WithDecoder, or when none is set only JSON responses
+// and text/plain responses are decoded and any other type fails with an UnexpectedContentType error
+func WithContentTypeDecoder(decoder Decoder, mediaTypes ...string) ClientOption {
+	return func(c *Client) error {
+		if c.decoders == nil {
+			c.decoders = make(map[string]Decoder)
+		}
+
+		for _, mediaType := range mediaTypes {
+			c.decoders[strings.ToLower(mediaType)] = decoder
+		}
+
+		return nil
+	}
+}
+
+// WithLogger logs the method, host, path, status code, duration and retry number of every attempt at debug level, including each
+// retry as its own entry. Request headers are included with the Authorization, Proxy-Authorization, Cookie, Set-Cookie, X-Api-Key and
+// Api-Key values redacted, more can be added with WithRedactedHeaders
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(c *Client) error {
+		c.logger = logger
+		return nil
+	}
+}
+
+// WithRedactedHeaders adds to the headers logged by WithLogger with their values redacted, such as a custom API key header
+func WithRedactedHeaders(names ...string) ClientOption {
+	return func(c *Client) error {
+		c.redactedHeaders = append(c.redactedHeaders, names...)
 		return nil
 	}
 }
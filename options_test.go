@@ -0,0 +1,1102 @@
+package httpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/throttled/throttled/v2"
+	"github.com/throttled/throttled/v2/store/memstore"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"golang.org/x/oauth2"
+)
+
+func TestCredentialsTokenTimeout(t *testing.T) {
+	release := make(chan struct{})
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(3 * time.Second):
+		}
+	}))
+	defer tokenSrv.Close()
+	defer close(release)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	cfg := &Config{BaseUrl: srv.URL, Timeout: int(200 * time.Millisecond)}
+
+	client, err := NewClient(context.Background(), cfg, WithCredentials(context.Background(), "id", "secret", tokenSrv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if _, err := client.Get(context.Background(), "/", nil, nil); err == nil {
+		t.Fatal("expected the token request to time out")
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the token request to be bounded by the client timeout, took %s", elapsed)
+	}
+}
+
+func TestDialTimeout(t *testing.T) {
+	// 10.255.255.1 is unroutable, so the dial hangs until the dial timeout
+	cfg := &Config{BaseUrl: "http://10.255.255.1", Timeout: int(5 * time.Second)}
+
+	client, err := NewClient(context.Background(), cfg, WithDialTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if _, err := client.Get(context.Background(), "/", nil, nil); err == nil {
+		t.Fatal("expected dialing an unroutable address to fail")
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected the dial timeout to fail the request before the client timeout, took %s", elapsed)
+	}
+}
+
+func TestAuthStyle(t *testing.T) {
+	tests := []struct {
+		name   string
+		style  oauth2.AuthStyle
+		header bool
+		param  bool
+	}{
+		{"in header", oauth2.AuthStyleInHeader, true, false},
+		{"in params", oauth2.AuthStyleInParams, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var header, secret string
+
+			tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.ParseForm()
+
+				mu.Lock()
+				header = r.Header.Get("Authorization")
+				secret = r.PostForm.Get("client_secret")
+				mu.Unlock()
+
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"access_token":"token","token_type":"bearer"}`))
+			}))
+			defer tokenSrv.Close()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			defer srv.Close()
+
+			client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL},
+				WithAuthStyle(tt.style),
+				WithCredentials(context.Background(), "id", "secret", tokenSrv.URL),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if (header != "") != tt.header {
+				t.Fatalf("expected credentials in the Authorization header %t, got %q", tt.header, header)
+			}
+
+			if (secret == "secret") != tt.param {
+				t.Fatalf("expected credentials in the body %t, got client_secret %q", tt.param, secret)
+			}
+		})
+	}
+}
+
+func TestRateLimitPerMethod(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithRateLimitPerMethod(map[string]int{"get": 6000, http.MethodPost: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Post(context.Background(), "/", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.Post(ctx, "/", nil, nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the second POST to be throttled, got %v", err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected GETs to be throttled independently of POSTs, took %s", elapsed)
+	}
+
+	// without a global limiter the remaining methods are unthrottled
+	for i := 0; i < 3; i++ {
+		if _, err := client.Put(context.Background(), "/", nil, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// headerServer starts a server that records the headers of the last request it received
+func headerServer(t *testing.T) (*httptest.Server, func() http.Header) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var last http.Header
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		last = r.Header.Clone()
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() http.Header {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return last
+	}
+}
+
+func TestBearerTokenAndBasicAuth(t *testing.T) {
+	srv, received := headerServer(t)
+
+	tests := []struct {
+		name    string
+		opts    []ClientOption
+		headers map[string]string
+		want    string
+	}{
+		{"bearer token", []ClientOption{WithBearerToken("token"), WithDefaultHeaders(map[string]string{"X-Default": "1"})}, nil, "Bearer token"},
+		{"per request override", []ClientOption{WithBearerToken("token")}, map[string]string{"Authorization": "Bearer other"}, "Bearer other"},
+		{"basic auth", []ClientOption{WithBasicAuth("user", "pass")}, nil, "Basic dXNlcjpwYXNz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := client.Get(context.Background(), "/", tt.headers, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := received().Get("Authorization"); got != tt.want {
+				t.Fatalf("expected Authorization %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithBearerToken("token"), WithDefaultHeaders(map[string]string{"X-Default": "1"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if received().Get("X-Default") != "1" {
+		t.Fatal("expected the token to merge with the other default headers")
+	}
+}
+
+func TestClientCredentialsEndpointParams(t *testing.T) {
+	var mu sync.Mutex
+	var audience string
+
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		mu.Lock()
+		audience = r.PostForm.Get("audience")
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"token","token_type":"bearer"}`))
+	}))
+	defer tokenSrv.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL},
+		WithCredentials(context.Background(), "id", "secret", tokenSrv.URL),
+		WithClientCredentialsEndpointParams(url.Values{"audience": {"https://api.example.com"}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if audience != "https://api.example.com" {
+		t.Fatalf("expected the token request to carry the audience, got %q", audience)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	srv, received := headerServer(t)
+
+	tests := []struct {
+		name    string
+		opts    []ClientOption
+		headers map[string]string
+		want    string
+	}{
+		{"default", nil, nil, DefaultUserAgent},
+		{"option", []ClientOption{WithUserAgent("service/1")}, nil, "service/1"},
+		{"per request override", []ClientOption{WithUserAgent("service/1")}, map[string]string{"User-Agent": "call/2"}, "call/2"},
+		{"default header", []ClientOption{WithDefaultHeaders(map[string]string{"user-agent": "lower/3"})}, nil, "lower/3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := client.Get(context.Background(), "/", tt.headers, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := received().Get("User-Agent"); got != tt.want {
+				t.Fatalf("expected User-Agent %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRateLimitKeyFunc(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	perPath := func(method, resource string) string {
+		return resource
+	}
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RateLimit: 1}, WithRateLimitKeyFunc(perPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/health", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/search", nil, nil); err != nil {
+		t.Fatalf("expected /search to have its own bucket, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.Get(ctx, "/health", nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the /health bucket to be exhausted, got %v", err)
+	}
+}
+
+func TestProxyForHosts(t *testing.T) {
+	var mu sync.Mutex
+	var proxied []string
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxy.Close()
+
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer direct.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: "http://internal.example"}, WithProxyForHosts(map[string]string{"internal.example": proxy.URL}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/a", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, direct.URL+"/b", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.DoRequest(req, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(proxied) != 1 || proxied[0] != "http://internal.example/a" {
+		t.Fatalf("expected only the listed host to be proxied, got %v", proxied)
+	}
+}
+
+func TestProxyFunc(t *testing.T) {
+	var mu sync.Mutex
+	var proxied string
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	proxyUrl, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxyFunc := func(req *http.Request) (*url.URL, error) {
+		return proxyUrl, nil
+	}
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: "http://anywhere.example"}, WithProxyFunc(proxyFunc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/c", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if proxied != "http://anywhere.example/c" {
+		t.Fatalf("expected the request to go through the proxy, got %q", proxied)
+	}
+}
+
+func TestRequiredResponseHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/signed" {
+			w.Header().Set("X-Signature", "abc")
+		}
+
+		w.Write([]byte(`{"a":1}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithRequiredResponseHeaders("X-Signature"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct{ A int }
+	_, err = client.Get(context.Background(), "/unsigned", nil, &decoded)
+
+	var missing *MissingHeader
+	if !errors.As(err, &missing) || missing.Header() != "X-Signature" {
+		t.Fatalf("expected *MissingHeader for X-Signature, got %v", err)
+	}
+
+	if decoded.A != 0 {
+		t.Fatal("expected the body not to be decoded when a required header is missing")
+	}
+
+	if _, err := client.Get(context.Background(), "/signed", nil, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.A != 1 {
+		t.Fatalf("expected the signed response to be decoded, got %+v", decoded)
+	}
+
+	result := client.Request(context.Background(), RequestSpec{Method: http.MethodGet, Resource: "/unsigned"})
+	if !errors.As(result.Err, &missing) {
+		t.Fatalf("expected the result to carry *MissingHeader, got %v", result.Err)
+	}
+}
+
+func TestTransportMiddleware(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var seen []string
+
+	recorder := func(name string) func(http.RoundTripper) http.RoundTripper {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				seen = append(seen, name+" "+req.URL.Path)
+				mu.Unlock()
+
+				return next.RoundTrip(req)
+			})
+		}
+	}
+
+	cfg := &Config{BaseUrl: srv.URL, RetryEnabled: true, RetryMaxDelay: time.Millisecond}
+
+	client, err := NewClient(context.Background(), cfg, WithTransportMiddleware(recorder("outer"), recorder("inner")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/users", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	want := []string{"outer /users", "inner /users", "outer /users", "inner /users"}
+	if len(seen) != len(want) {
+		t.Fatalf("expected the middleware to see the original and retried attempts %v, got %v", want, seen)
+	}
+
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, seen)
+		}
+	}
+}
+
+func TestRequestAndResponseHooks(t *testing.T) {
+	var calls atomic.Int64
+	var correlationId atomic.Value
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		correlationId.Store(r.Header.Get("X-Correlation-Id"))
+
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	var requests, responses, lastStatus int
+
+	requestHook := func(req *http.Request) {
+		requests++
+		req.Header.Set("X-Correlation-Id", "cid")
+	}
+
+	responseHook := func(resp *http.Response, err error) {
+		responses++
+		lastStatus = resp.StatusCode
+	}
+
+	cfg := &Config{BaseUrl: srv.URL, RetryEnabled: true, RetryMaxDelay: time.Millisecond}
+
+	client, err := NewClient(context.Background(), cfg, WithRequestHook(requestHook), WithResponseHook(responseHook))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls.Load() != 2 {
+		t.Fatalf("expected the first attempt to be retried, got %d calls", calls.Load())
+	}
+
+	if requests != 1 || responses != 1 {
+		t.Fatalf("expected the hooks to run once per call rather than per retry, got %d and %d", requests, responses)
+	}
+
+	if correlationId.Load() != "cid" || lastStatus != http.StatusOK {
+		t.Fatalf("expected the hooks to see the request and final response, got %v and %d", correlationId.Load(), lastStatus)
+	}
+
+	if _, err := client.Get(context.Background(), "/missing", nil, nil); err == nil {
+		t.Fatal("expected a status error")
+	}
+
+	if responses != 2 || lastStatus != http.StatusNotFound {
+		t.Fatalf("expected the response hook to run when the status check fails, got %d calls with status %d", responses, lastStatus)
+	}
+}
+
+func TestRequestValidator(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	errMissingTenant := errors.New("missing X-Tenant")
+
+	validator := func(req *http.Request) error {
+		if req.Header.Get("X-Tenant") == "" {
+			return errMissingTenant
+		}
+
+		return nil
+	}
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithRequestValidator(validator))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Get(context.Background(), "/", nil, nil)
+
+	var invalid *InvalidRequest
+	if !errors.As(err, &invalid) || !errors.Is(err, errMissingTenant) {
+		t.Fatalf("expected *InvalidRequest wrapping the validator error, got %v", err)
+	}
+
+	if hits.Load() != 0 {
+		t.Fatal("expected the invalid request not to be sent")
+	}
+
+	if _, err := client.Get(context.Background(), "/", map[string]string{"X-Tenant": "acme"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.DoRequest(req, nil); !errors.As(err, &invalid) {
+		t.Fatalf("expected DoRequest to be validated too, got %v", err)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var mu sync.Mutex
+	var proxied []string
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxy.Close()
+
+	// retry and otel wrap the transport, the proxy must survive both
+	cfg := &Config{BaseUrl: "http://api.example", RetryEnabled: true, OTelEnabled: true}
+
+	client, err := NewClient(context.Background(), cfg, WithProxy(proxy.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/a", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(proxied) != 1 || proxied[0] != "http://api.example/a" {
+		t.Fatalf("expected the request to go through the proxy, got %v", proxied)
+	}
+}
+
+func TestProxyInvalid(t *testing.T) {
+	_, err := NewClient(context.Background(), &Config{BaseUrl: "http://api.example"}, WithProxy("nohost"))
+
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("expected *ConfigError for a proxy without a host, got %v", err)
+	}
+}
+
+func TestDefaultCookieJar(t *testing.T) {
+	var mu sync.Mutex
+	var session string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			return
+		}
+
+		if cookie, err := r.Cookie("session"); err == nil {
+			mu.Lock()
+			session = cookie.Value
+			mu.Unlock()
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		opts []ClientOption
+	}{
+		{"default client", []ClientOption{WithDefaultCookieJar()}},
+		{"custom client", []ClientOption{WithDefaultCookieJar(), WithCustomClient(&http.Client{})}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			session = ""
+			mu.Unlock()
+
+			client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := client.Post(context.Background(), "/login", nil, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := client.Get(context.Background(), "/me", nil, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if session != "abc" {
+				t.Fatalf("expected the session cookie to be sent, got %q", session)
+			}
+		})
+	}
+}
+
+// issueCert creates a certificate from template signed by parent, or self signed when parent is nil, returning it with PEM encoded cert and key
+func issueCert(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+
+	return cert, key, certPem, keyPem
+}
+
+func TestClientCert(t *testing.T) {
+	ca, caKey, caPem, _ := issueCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+
+	_, _, serverPem, serverKeyPem := issueCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+
+	_, _, clientPem, clientKeyPem := issueCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "client"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+
+	for file, data := range map[string][]byte{caFile: caPem, certFile: clientPem, keyFile: clientKeyPem} {
+		if err := os.WriteFile(file, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	serverCert, err := tls.X509KeyPair(serverPem, serverKeyPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	var mu sync.Mutex
+	var commonName string
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		commonName = r.TLS.PeerCertificates[0].Subject.CommonName
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}, ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	srv.StartTLS()
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithClientCert(certFile, keyFile, caFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if commonName != "client" {
+		t.Fatalf("expected the server to verify the client certificate, got common name %q", commonName)
+	}
+
+	var certErr *CertificateError
+
+	if _, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithClientCert(certFile, keyFile, keyFile)); !errors.As(err, &certErr) {
+		t.Fatalf("expected *CertificateError for a CA file without certificates, got %v", err)
+	}
+
+	if _, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithClientCert("missing.pem", "missing.pem", "")); !errors.As(err, &certErr) {
+		t.Fatalf("expected *CertificateError for missing files, got %v", err)
+	}
+}
+
+// countingStore wraps a GCRAStoreCtx and counts reads
+type countingStore struct {
+	throttled.GCRAStoreCtx
+	reads atomic.Int32
+}
+
+func (s *countingStore) GetWithTime(ctx context.Context, key string) (int64, time.Time, error) {
+	s.reads.Add(1)
+	return s.GCRAStoreCtx.GetWithTime(ctx, key)
+}
+
+func TestRateLimiterStore(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	memStore, err := memstore.NewCtx(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := &countingStore{GCRAStoreCtx: memStore}
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RateLimit: 1}, WithRateLimiterStore(store, 600))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := store.reads.Load(); n < 2 {
+		t.Fatalf("expected the rate limiter to use the provided store, got %d reads", n)
+	}
+
+	// two instances sharing the store share their per method limits, without colliding with the main limiter
+	instances := make([]*Client, 2)
+	for i := range instances {
+		instances[i], err = NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithRateLimiterStore(store, 6000), WithRateLimitPerMethod(map[string]int{http.MethodPost: 60}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := instances[0].Post(context.Background(), "/", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := instances[1].Post(ctx, "/", nil, nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the per method limit to be shared through the store, got %v", err)
+	}
+
+	if _, err := instances[1].Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCredentialScopes(t *testing.T) {
+	var mu sync.Mutex
+	var scope string
+
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		mu.Lock()
+		scope = r.PostForm.Get("scope")
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"token","token_type":"bearer"}`))
+	}))
+	defer tokenSrv.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithCredentials(context.Background(), "id", "secret", tokenSrv.URL, "read", "write"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if scope != "read write" {
+		t.Fatalf("expected the token request to carry the scopes, got %q", scope)
+	}
+}
+
+// TestOptions smoke tests every ClientOption against a plain client and one wrapped for retries and tracing
+func TestOptions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path == "/token" {
+			w.Write([]byte(`{"access_token":"token","token_type":"bearer"}`))
+			return
+		}
+
+		w.Header().Set("X-Request-Id", "1")
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	_, _, certPem, keyPem := issueCert(t, &x509.Certificate{SerialNumber: big.NewInt(1), IsCA: true, BasicConstraintsValid: true}, nil, nil)
+
+	certFile := filepath.Join(t.TempDir(), "cert.pem")
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+
+	if err := os.WriteFile(certFile, certPem, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(keyFile, keyPem, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := memstore.NewCtx(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := map[string]ClientOption{
+		"WithCustomClient":                    WithCustomClient(&http.Client{}),
+		"WithDefaultHeaders":                  WithDefaultHeaders(map[string]string{"X-Default": "1"}),
+		"WithBearerToken":                     WithBearerToken("token"),
+		"WithBasicAuth":                       WithBasicAuth("user", "pass"),
+		"WithUserAgent":                       WithUserAgent("httpc-test"),
+		"WithCredentials":                     WithCredentials(context.Background(), "id", "secret", srv.URL+"/token", "read"),
+		"WithAuthStyle":                       WithAuthStyle(oauth2.AuthStyleInHeader),
+		"WithClientCredentialsEndpointParams": WithClientCredentialsEndpointParams(url.Values{"audience": {"api"}}),
+		"WithRateLimiter":                     WithRateLimiter(6000),
+		"WithRateLimiterStore":                WithRateLimiterStore(store, 6000),
+		"WithRateLimitPerMethod":              WithRateLimitPerMethod(map[string]int{http.MethodGet: 6000}),
+		"WithRateLimitBurst":                  WithRateLimitBurst(5),
+		"WithRateLimitKeyFunc":                WithRateLimitKeyFunc(func(method, resource string) string { return method }),
+		"WithAcceptGzip":                      WithAcceptGzip(true),
+		"WithDecompression":                   WithDecompression(),
+		"WithMaxRequestsPerConnection":        WithMaxRequestsPerConnection(10),
+		"WithBodyBufferPool":                  WithBodyBufferPool(1024),
+		"WithOTelSpanNameFormatter":           WithOTelSpanNameFormatter(func(operation string, req *http.Request) string { return operation }),
+		"WithOTelAttributes":                  WithOTelAttributes(attribute.String("service", "test")),
+		"WithPropagator":                      WithPropagator(propagation.TraceContext{}),
+		"WithSpanAttributesFromContext":       WithSpanAttributesFromContext(func(ctx context.Context) []attribute.KeyValue { return nil }),
+		"WithResponseCache":                   WithResponseCache(10),
+		"WithPathNormalization":               WithPathNormalization(),
+		"WithMetricsObserver":                 WithMetricsObserver(func(metrics RequestMetrics) {}),
+		"WithMaxResponseBytes":                WithMaxResponseBytes(1024),
+		"WithDialTimeout":                     WithDialTimeout(time.Second),
+		"WithURLRewriter":                     WithURLRewriter(func(u *url.URL) (*url.URL, error) { return u, nil }),
+		"WithDeadlineHeader":                  WithDeadlineHeader("X-Deadline"),
+		"WithMaxConcurrentPerHost":            WithMaxConcurrentPerHost(2),
+		"WithMaxConcurrentRequests":           WithMaxConcurrentRequests(2),
+		"WithBufferedDecode":                  WithBufferedDecode(),
+		"WithResponseBodyReplay":              WithResponseBodyReplay(),
+		"WithProxyFunc":                       WithProxyFunc(func(req *http.Request) (*url.URL, error) { return nil, nil }),
+		"WithClientCert":                      WithClientCert(certFile, keyFile, certFile),
+		"WithBaseURLPath":                     WithBaseURLPath("/api"),
+		"WithProxy":                           WithProxy(srv.URL),
+		"WithProxyFromEnvironment":            WithProxyFromEnvironment(),
+		"WithProxyForHosts":                   WithProxyForHosts(map[string]string{"api.example": srv.URL}),
+		"WithRequiredResponseHeaders":         WithRequiredResponseHeaders("X-Request-Id"),
+		"WithTransportMiddleware":             WithTransportMiddleware(func(rt http.RoundTripper) http.RoundTripper { return rt }),
+		"WithRequestHook":                     WithRequestHook(func(req *http.Request) {}),
+		"WithResponseHook":                    WithResponseHook(func(resp *http.Response, err error) {}),
+		"WithHealthPath":                      WithHealthPath("/health"),
+		"WithCircuitBreaker":                  WithCircuitBreaker(5, time.Second),
+		"WithCaptureRedirects":                WithCaptureRedirects(),
+		"WithRequestValidator":                WithRequestValidator(func(req *http.Request) error { return nil }),
+		"WithCookieJar":                       WithCookieJar(jar),
+		"WithDefaultCookieJar":                WithDefaultCookieJar(),
+		"WithDecoder":                         WithDecoder(JSONDecoder{}),
+		"WithXMLDecoder":                      WithXMLDecoder(),
+		"WithContentTypeDecoder":              WithContentTypeDecoder(JSONDecoder{}, "application/json"),
+		"WithLogger":                          WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		"WithRedactedHeaders":                 WithRedactedHeaders("X-Tenant-Token"),
+	}
+
+	configs := map[string]func() *Config{
+		"plain":   func() *Config { return &Config{BaseUrl: srv.URL} },
+		"wrapped": func() *Config { return &Config{BaseUrl: srv.URL, RetryEnabled: true, OTelEnabled: true} },
+	}
+
+	for configName, config := range configs {
+		for name, opt := range options {
+			t.Run(configName+"/"+name, func(t *testing.T) {
+				client, err := NewClient(context.Background(), config(), opt)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var out map[string]any
+
+				if _, err := client.Get(context.Background(), "/", nil, &out); err != nil {
+					t.Fatal(err)
+				}
+			})
+		}
+	}
+}
+
+func TestCustomClientTransportOptions(t *testing.T) {
+	var mu sync.Mutex
+	var proxied []string
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxy.Close()
+
+	transport := &http.Transport{}
+	custom := &http.Client{Transport: transport}
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: "http://api.example"}, WithCustomClient(custom), WithProxy(proxy.URL), WithDialTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/a", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(proxied) != 1 || proxied[0] != "http://api.example/a" {
+		t.Fatalf("expected the custom client's request to go through the proxy, got %v", proxied)
+	}
+
+	if transport.Proxy != nil || transport.DialContext != nil {
+		t.Fatal("expected the custom client's transport to be left unmodified")
+	}
+
+	opaque := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) { return nil, errors.New("unused") })}
+
+	for name, opt := range map[string]ClientOption{"proxy": WithProxy(proxy.URL), "dial timeout": WithDialTimeout(time.Second)} {
+		var configErr *ConfigError
+
+		if _, err := NewClient(context.Background(), &Config{BaseUrl: "http://api.example"}, WithCustomClient(opaque), opt); !errors.As(err, &configErr) {
+			t.Fatalf("%s: expected *ConfigError for a transport that can't be configured, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package httpc
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanAttributesTransport adds attributes derived from the request context to the span created by the otel transport
+type spanAttributesTransport struct {
+	transport  http.RoundTripper
+	attributes func(ctx context.Context) []attribute.KeyValue
+}
+
+// RoundTrip implements the http.RoundTripper interface, applying the attributes before the request is sent
+func (t *spanAttributesTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if attrs := t.attributes(req.Context()); len(attrs) > 0 {
+		trace.SpanFromContext(req.Context()).SetAttributes(attrs...)
+	}
+
+	return t.transport.RoundTrip(req)
+}
+
+// propagationTransport injects the trace context and baggage of the request context into the request headers when OTel is disabled
+// and the otel transport isn't there to do it
+type propagationTransport struct {
+	transport  http.RoundTripper
+	propagator propagation.TextMapPropagator
+}
+
+// RoundTrip implements the http.RoundTripper interface, injecting the propagation headers into a copy of the request
+func (t *propagationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	t.propagator.Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+
+	return t.transport.RoundTrip(req)
+}
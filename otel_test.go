@@ -0,0 +1,382 @@
+package httpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+// recordingProvider is an in-memory trace.TracerProvider that keeps every span it starts
+type recordingProvider struct {
+	embedded.TracerProvider
+
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+// withRecordingProvider installs a recordingProvider as the global tracer provider for the duration of the test
+func withRecordingProvider(t *testing.T) *recordingProvider {
+	t.Helper()
+
+	provider := &recordingProvider{}
+
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	return provider
+}
+
+func (p *recordingProvider) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	return &recordingTracer{provider: p}
+}
+
+func (p *recordingProvider) Spans() []*recordedSpan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]*recordedSpan(nil), p.spans...)
+}
+
+type recordingTracer struct {
+	embedded.Tracer
+
+	provider *recordingProvider
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+
+	span := &recordedSpan{provider: t.provider, name: name, attrs: cfg.Attributes(), parent: trace.SpanContextFromContext(ctx)}
+
+	t.provider.mu.Lock()
+	t.provider.spans = append(t.provider.spans, span)
+	t.provider.mu.Unlock()
+
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordedSpan struct {
+	embedded.Span
+
+	provider *recordingProvider
+	parent   trace.SpanContext
+
+	mu    sync.Mutex
+	name  string
+	attrs []attribute.KeyValue
+}
+
+// Attribute returns the last value recorded for key
+func (s *recordedSpan) Attribute(key attribute.Key) (attribute.Value, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.attrs) - 1; i >= 0; i-- {
+		if s.attrs[i].Key == key {
+			return s.attrs[i].Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+}
+
+func (s *recordedSpan) Name() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.name
+}
+
+func (s *recordedSpan) End(options ...trace.SpanEndOption)                  {}
+func (s *recordedSpan) AddEvent(name string, options ...trace.EventOption)  {}
+func (s *recordedSpan) AddLink(link trace.Link)                             {}
+func (s *recordedSpan) IsRecording() bool                                   { return true }
+func (s *recordedSpan) RecordError(err error, options ...trace.EventOption) {}
+func (s *recordedSpan) SpanContext() trace.SpanContext                      { return s.parent }
+func (s *recordedSpan) SetStatus(code codes.Code, description string)       {}
+func (s *recordedSpan) TracerProvider() trace.TracerProvider                { return s.provider }
+
+func (s *recordedSpan) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.name = name
+}
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attrs = append(s.attrs, kv...)
+}
+
+func TestRetryCountAttribute(t *testing.T) {
+	provider := withRecordingProvider(t)
+
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RetryEnabled: true, OTelEnabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := provider.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	value, ok := spans[0].Attribute(RetryCountAttribute)
+	if !ok || value.AsInt64() != 1 {
+		t.Fatalf("expected %s of 1, got %v (present %t)", RetryCountAttribute, value.Emit(), ok)
+	}
+}
+
+func TestRetryCountAttributeWithoutRetry(t *testing.T) {
+	provider := withRecordingProvider(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, OTelEnabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, span := range provider.Spans() {
+		if _, ok := span.Attribute(RetryCountAttribute); ok {
+			t.Fatalf("expected no %s attribute when retries are disabled", RetryCountAttribute)
+		}
+	}
+}
+
+type tenantKey struct{}
+
+func TestSpanAttributesFromContext(t *testing.T) {
+	provider := withRecordingProvider(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	tenantAttributes := func(ctx context.Context) []attribute.KeyValue {
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		return []attribute.KeyValue{attribute.String("tenant", tenant)}
+	}
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, OTelEnabled: true}, WithSpanAttributesFromContext(tenantAttributes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	if _, err := client.Get(ctx, "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := provider.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	if value, ok := spans[0].Attribute("tenant"); !ok || value.AsString() != "acme" {
+		t.Fatalf("expected tenant attribute acme, got %q (present %t)", value.AsString(), ok)
+	}
+}
+
+func TestCustomClientTraced(t *testing.T) {
+	provider := withRecordingProvider(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	custom := &http.Client{}
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, OTelEnabled: true}, WithCustomClient(custom))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(provider.Spans()) != 1 {
+		t.Fatalf("expected the custom client's requests to be traced, got %d spans", len(provider.Spans()))
+	}
+
+	if custom.Transport != nil {
+		t.Fatal("expected the custom client to be left unmodified")
+	}
+}
+
+func TestSpanNameAndAttributes(t *testing.T) {
+	provider := withRecordingProvider(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	spanName := func(operation string, r *http.Request) string {
+		return "api " + r.Method + " " + r.URL.Path
+	}
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, OTelEnabled: true}, WithOTelSpanNameFormatter(spanName), WithOTelAttributes(attribute.String("api.version", "v2")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/users", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := provider.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	if name := spans[0].Name(); name != "api GET /users" {
+		t.Fatalf("expected the formatted span name, got %q", name)
+	}
+
+	if value, ok := spans[0].Attribute("api.version"); !ok || value.AsString() != "v2" {
+		t.Fatalf("expected api.version attribute v2, got %q (present %t)", value.AsString(), ok)
+	}
+}
+
+func TestPropagator(t *testing.T) {
+	var mu sync.Mutex
+	var traceparent, bag string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		traceparent, bag = r.Header.Get("traceparent"), r.Header.Get("baggage")
+	}))
+	defer srv.Close()
+
+	traceID := trace.TraceID{1}
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: trace.SpanID{2}, TraceFlags: trace.FlagsSampled})
+
+	member, err := baggage.NewMember("tenant", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	members, err := baggage.New(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := baggage.ContextWithBaggage(trace.ContextWithSpanContext(context.Background(), spanContext), members)
+	propagator := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+	for _, otelEnabled := range []bool{false, true} {
+		if otelEnabled {
+			withRecordingProvider(t)
+		}
+
+		client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, OTelEnabled: otelEnabled}, WithPropagator(propagator))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := client.Get(ctx, "/", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		mu.Lock()
+		gotTraceparent, gotBaggage := traceparent, bag
+		mu.Unlock()
+
+		if !strings.Contains(gotTraceparent, traceID.String()) {
+			t.Fatalf("otel enabled %t: expected traceparent with trace id %s, got %q", otelEnabled, traceID, gotTraceparent)
+		}
+
+		if gotBaggage != "tenant=acme" {
+			t.Fatalf("otel enabled %t: expected baggage tenant=acme, got %q", otelEnabled, gotBaggage)
+		}
+	}
+}
+
+func TestDefaultPropagator(t *testing.T) {
+	withRecordingProvider(t)
+
+	var mu sync.Mutex
+	var traceparent, bag string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		traceparent, bag = r.Header.Get("traceparent"), r.Header.Get("baggage")
+	}))
+	defer srv.Close()
+
+	traceID := trace.TraceID{1}
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: trace.SpanID{2}, TraceFlags: trace.FlagsSampled})
+
+	member, err := baggage.NewMember("tenant", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	members, err := baggage.New(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := baggage.ContextWithBaggage(trace.ContextWithSpanContext(context.Background(), spanContext), members)
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, OTelEnabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(ctx, "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !strings.Contains(traceparent, traceID.String()) {
+		t.Fatalf("expected traceparent with trace id %s without a global propagator, got %q", traceID, traceparent)
+	}
+
+	if bag != "tenant=acme" {
+		t.Fatalf("expected baggage tenant=acme without a global propagator, got %q", bag)
+	}
+}
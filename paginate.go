@@ -0,0 +1,87 @@
+package httpc
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Paginate makes a GET request to the supplied endpoint and yields each page's body, calling next with the response and body to
+// compute the resource of the following page until it returns an empty string. Every page waits on the rate limiter. Iteration stops
+// after yielding the first error, including errors returned by next and the context error
+func (c *Client) Paginate(ctx context.Context, resource string, headers map[string]string, next func(resp *http.Response, body []byte) (string, error)) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		for resource != "" {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			body, resp, err := c.readBody(ctx, http.MethodGet, resource, headers)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(body, nil) {
+				return
+			}
+
+			resource, err = next(resp, body)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
+
+// NextLink returns the target of the Link header with rel="next", resolved against the request url, for use with Paginate.
+// It returns an empty string when there is no next link
+func NextLink(resp *http.Response, _ []byte) (string, error) {
+	for _, header := range resp.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			if !hasRel(params, "next") {
+				continue
+			}
+
+			ref, err := url.Parse(strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">"))
+			if err != nil {
+				return "", &InvalidResource{err}
+			}
+
+			if resp.Request != nil {
+				ref = resp.Request.URL.ResolveReference(ref)
+			}
+
+			return ref.String(), nil
+		}
+	}
+
+	return "", nil
+}
+
+// hasRel reports whether the link params include the relation type, which may be one of several space separated types
+func hasRel(params string, rel string) bool {
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if !strings.EqualFold(strings.TrimSpace(name), "rel") {
+			continue
+		}
+
+		for _, relType := range strings.Fields(strings.Trim(strings.TrimSpace(value), `"`)) {
+			if strings.EqualFold(relType, rel) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,83 @@
+package httpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	var requests atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		page := r.URL.Query().Get("page")
+
+		switch page {
+		case "":
+			w.Header().Set("Link", `</items?page=2>; rel="next", </items?page=9>; rel="last"`)
+		case "2":
+			w.Header().Set("Link", `<http://`+r.Host+`/items?page=3>; rel="prev next"`)
+		}
+
+		w.Write([]byte("page" + page))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RateLimit: 6000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pages []string
+
+	for body, err := range client.Paginate(context.Background(), "/items", nil, NextLink) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pages = append(pages, string(body))
+	}
+
+	want := []string{"page", "page2", "page3"}
+
+	if len(pages) != len(want) {
+		t.Fatalf("expected pages %v, got %v", want, pages)
+	}
+
+	for i := range want {
+		if pages[i] != want[i] {
+			t.Fatalf("expected pages %v, got %v", want, pages)
+		}
+	}
+
+	requests.Store(0)
+
+	for range client.Paginate(context.Background(), "/items", nil, NextLink) {
+		break
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("expected breaking out of the loop to stop fetching, got %d requests", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var errs int
+
+	for _, err := range client.Paginate(ctx, "/items", nil, NextLink) {
+		cancel()
+
+		if err != nil {
+			errs++
+		}
+	}
+
+	if errs != 1 {
+		t.Fatalf("expected a single error after cancellation, got %d", errs)
+	}
+}
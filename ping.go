@@ -0,0 +1,70 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const DefaultPingTimeout time.Duration = 2 * time.Second
+
+// Ping makes a HEAD request to the health path, or the base url when none is configured, and returns the round trip time. Upstreams that
+// reject HEAD with 405 Method Not Allowed are probed again with a GET, whose body is never read, and only the probe that succeeded is timed.
+// Probes are never answered from the response cache or subject to rate limiting, and use DefaultPingTimeout in place of the client timeout.
+// DNS and connection failures are returned as a RequestError and non 2XX responses as a BadStatusCode
+func (c *Client) Ping(ctx context.Context) (time.Duration, error) {
+	target := c.BaseUrl
+	if c.healthPath != "" {
+		resolved, err := c.resolve(c.healthPath)
+		if err != nil {
+			return 0, err
+		}
+
+		target = resolved
+	}
+
+	ctx = context.WithValue(ctx, timeoutKey{}, DefaultPingTimeout)
+
+	rtt, err := c.probe(ctx, http.MethodHead, target)
+
+	var statusErr *BadStatusCode
+	if errors.As(err, &statusErr) && statusErr.StatusCode() == http.StatusMethodNotAllowed {
+		rtt, err = c.probe(ctx, http.MethodGet, target)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return rtt, nil
+}
+
+// probe sends a request with the default headers to the target and returns its round trip time, closing the response without reading its body
+func (c *Client) probe(ctx context.Context, method string, target *url.URL) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	for key, val := range c.Headers {
+		req.Header.Set(key, val)
+	}
+
+	// a cached response says nothing about whether the upstream is reachable now
+	req.Header.Set("Cache-Control", "no-cache")
+
+	start := time.Now()
+
+	resp, err := c.execute(req)
+	if err != nil {
+		return 0, err
+	}
+
+	rtt := time.Since(start)
+
+	resp.Body.Close()
+
+	return rtt, nil
+}
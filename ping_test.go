@@ -0,0 +1,182 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPingFallback(t *testing.T) {
+	var mu sync.Mutex
+	var methods []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(methods) != 2 || methods[0] != http.MethodHead || methods[1] != http.MethodGet {
+		t.Fatalf("expected a HEAD followed by a GET, got %v", methods)
+	}
+
+	down, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithHealthPath("/down"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := down.Ping(context.Background()); !errors.Is(err, ErrBadStatus) {
+		t.Fatalf("expected a bad status for an unavailable upstream, got %v", err)
+	}
+
+	srv.Close()
+
+	if _, err := client.Ping(context.Background()); !errors.Is(err, ErrRequest) {
+		t.Fatalf("expected a request error for an unreachable upstream, got %v", err)
+	}
+}
+
+func TestPingBypassesCache(t *testing.T) {
+	var down atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithResponseCache(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GetBytes(context.Background(), "/", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	down.Store(true)
+
+	if _, err := client.Ping(context.Background()); !errors.Is(err, ErrBadStatus) {
+		t.Fatalf("expected the probe to reach the upstream rather than the cache, got %v", err)
+	}
+}
+
+func TestPingTimesSuccessfulProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			time.Sleep(200 * time.Millisecond)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rtt, err := client.Ping(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rtt <= 0 || rtt >= 200*time.Millisecond {
+		t.Fatalf("expected only the GET probe to be timed, got %s", rtt)
+	}
+}
+
+func TestPing(t *testing.T) {
+	var mu sync.Mutex
+	var method, path string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		method, path = r.Method, r.URL.Path
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RateLimit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// spend the rate limiter's only token, Ping should not wait on it
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	rtt, err := client.Ping(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rtt < 5*time.Millisecond || rtt > time.Second {
+		t.Fatalf("expected a plausible round trip time, got %s", rtt)
+	}
+
+	mu.Lock()
+	if method != http.MethodHead {
+		t.Fatalf("expected a HEAD probe, got %s", method)
+	}
+	mu.Unlock()
+
+	health, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithHealthPath("/down"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = health.Ping(context.Background())
+
+	var statusErr *BadStatusCode
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected a status error from an unhealthy server, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if path != "/down" {
+		t.Fatalf("expected the health path to be probed, got %s", path)
+	}
+}
@@ -0,0 +1,76 @@
+package httpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// bufferPool reuses buffers when reading response bodies up to size bytes. A nil pool reads bodies without pooling
+type bufferPool struct {
+	pool sync.Pool
+	size int
+}
+
+func newBufferPool(size int) *bufferPool {
+	return &bufferPool{
+		pool: sync.Pool{
+			New: func() any {
+				return bytes.NewBuffer(make([]byte, 0, size))
+			},
+		},
+		size: size,
+	}
+}
+
+func (p *bufferPool) get() *bytes.Buffer {
+	return p.pool.Get().(*bytes.Buffer)
+}
+
+// put returns the buffer to the pool, dropping buffers that grew past the pooled size
+func (p *bufferPool) put(buf *bytes.Buffer) {
+	if buf.Cap() > p.size {
+		return
+	}
+
+	buf.Reset()
+	p.pool.Put(buf)
+}
+
+// readAll reads the body into a pooled buffer and returns an exactly sized copy
+func (p *bufferPool) readAll(r io.Reader) ([]byte, error) {
+	if p == nil {
+		return io.ReadAll(r)
+	}
+
+	buf := p.get()
+	defer p.put(buf)
+
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+// decode reads small bodies into a pooled buffer before unmarshalling them, falling back to a streaming decoder for bodies larger than the pool size
+func (p *bufferPool) decode(r io.Reader, decoded interface{}) error {
+	if p == nil {
+		return json.NewDecoder(r).Decode(decoded)
+	}
+
+	buf := p.get()
+	defer p.put(buf)
+
+	n, err := buf.ReadFrom(io.LimitReader(r, int64(p.size)+1))
+	if err != nil {
+		return err
+	}
+
+	if n > int64(p.size) {
+		return json.NewDecoder(io.MultiReader(bytes.NewReader(buf.Bytes()), r)).Decode(decoded)
+	}
+
+	return json.Unmarshal(buf.Bytes(), decoded)
+}
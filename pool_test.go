@@ -0,0 +1,58 @@
+package httpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBufferPoolReadAll(t *testing.T) {
+	pool := newBufferPool(8)
+
+	for _, body := range []string{"small", "larger than the pooled size"} {
+		data, err := pool.readAll(strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != body {
+			t.Fatalf("expected %q, got %q", body, data)
+		}
+	}
+
+	if buf := pool.get(); buf.Cap() > 8 || buf.Len() != 0 {
+		t.Fatalf("expected a reset buffer within the pooled size, got len %d cap %d", buf.Len(), buf.Cap())
+	}
+}
+
+func benchmarkGet(b *testing.B, opts ...ClientOption) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"gopher","tags":["a","b","c"],"count":12345}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, opts...)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var out struct{ Name string }
+		if _, err := client.Get(context.Background(), "/", nil, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	benchmarkGet(b)
+}
+
+func BenchmarkGetPooled(b *testing.B) {
+	benchmarkGet(b, WithBodyBufferPool(4096))
+}
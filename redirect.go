@@ -0,0 +1,70 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// maxRedirects matches the number of redirects the default http.Client policy follows
+const maxRedirects int = 10
+
+// RedirectHop is a redirect response that was followed on the way to the final response
+type RedirectHop struct {
+	URL        *url.URL
+	StatusCode int
+	Location   string
+}
+
+// redirectsKey holds the redirect log of a request in its context
+type redirectsKey struct{}
+
+type redirectLog struct {
+	hops []RedirectHop
+}
+
+// Redirects returns the redirect hops followed to reach the response in the order they happened. It returns nil when no redirects were
+// followed or the client wasn't created with WithCaptureRedirects
+func Redirects(resp *http.Response) []RedirectHop {
+	if resp == nil || resp.Request == nil {
+		return nil
+	}
+
+	log, ok := resp.Request.Context().Value(redirectsKey{}).(*redirectLog)
+	if !ok {
+		return nil
+	}
+
+	return log.hops
+}
+
+// withRedirectLog attaches an empty redirect log to the request context
+func withRedirectLog(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), redirectsKey{}, &redirectLog{}))
+}
+
+// captureRedirects wraps the redirect policy so every redirect it allows is recorded in the request's redirect log. A nil policy
+// falls back to the default of following up to 10 redirects
+func captureRedirects(checkRedirect func(req *http.Request, via []*http.Request) error) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if checkRedirect != nil {
+			if err := checkRedirect(req, via); err != nil {
+				return err
+			}
+		} else if len(via) >= maxRedirects {
+			return errors.New("stopped after 10 redirects")
+		}
+
+		log, ok := req.Context().Value(redirectsKey{}).(*redirectLog)
+		if ok && req.Response != nil {
+			log.hops = append(log.hops, RedirectHop{
+				URL:        via[len(via)-1].URL,
+				StatusCode: req.Response.StatusCode,
+				Location:   req.Response.Header.Get("Location"),
+			})
+		}
+
+		return nil
+	}
+}
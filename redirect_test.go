@@ -0,0 +1,66 @@
+package httpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCaptureRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusMovedPermanently)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		cfg  *Config
+	}{
+		{"plain", &Config{BaseUrl: srv.URL}},
+		{"traced", &Config{BaseUrl: srv.URL, OTelEnabled: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(context.Background(), tt.cfg, WithCaptureRedirects())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := client.Get(context.Background(), "/a", nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hops := Redirects(resp)
+			if len(hops) != 2 {
+				t.Fatalf("expected 2 redirect hops, got %+v", hops)
+			}
+
+			if hops[0].URL.Path != "/a" || hops[0].StatusCode != http.StatusFound || hops[0].Location != "/b" {
+				t.Fatalf("unexpected first hop %+v", hops[0])
+			}
+
+			if hops[1].URL.Path != "/b" || hops[1].StatusCode != http.StatusMovedPermanently || hops[1].Location != "/c" {
+				t.Fatalf("unexpected second hop %+v", hops[1])
+			}
+
+			resp, err = client.Get(context.Background(), "/c", nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if hops := Redirects(resp); hops != nil {
+				t.Fatalf("expected no hops without a redirect, got %+v", hops)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package httpc
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+)
+
+// replayBody holds a request body so it can be sent again on every retry attempt. Bodies are replayed with GetBody when the
+// request provides it, otherwise they are buffered in memory or spilled to a temp file past the spill threshold
+type replayBody struct {
+	getBody func() (io.ReadCloser, error)
+	data    []byte
+	file    *os.File
+	size    int64
+	sent    bool
+}
+
+// replayableBody prepares the request body for replay. A nil replayBody is returned for bodyless requests
+func (t *RetryTransport) replayableBody(req *http.Request) (*replayBody, error) {
+	if !hasBody(req) {
+		return nil, nil
+	}
+
+	if req.GetBody != nil {
+		return &replayBody{getBody: req.GetBody}, nil
+	}
+
+	defer req.Body.Close()
+
+	var src io.Reader = req.Body
+	if t.bodyLimit > 0 {
+		if req.ContentLength > t.bodyLimit {
+			return nil, &RetryBodyTooLarge{t.bodyLimit, req.ContentLength}
+		}
+
+		src = &limitedReader{r: req.Body, limit: t.bodyLimit}
+	}
+
+	if t.spillThreshold <= 0 {
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return nil, bufferError(err)
+		}
+
+		return &replayBody{data: data}, nil
+	}
+
+	buf := &bytes.Buffer{}
+	if _, err := io.CopyN(buf, src, t.spillThreshold+1); err != nil && err != io.EOF {
+		return nil, bufferError(err)
+	}
+
+	if int64(buf.Len()) <= t.spillThreshold {
+		return &replayBody{data: buf.Bytes()}, nil
+	}
+
+	return spillBody(io.MultiReader(buf, src))
+}
+
+// spillBody copies the body to a temp file that is removed once the body is closed
+func spillBody(r io.Reader) (*replayBody, error) {
+	file, err := os.CreateTemp("", "httpc-body-*")
+	if err != nil {
+		return nil, &CopyError{err}
+	}
+
+	body := &replayBody{file: file}
+
+	body.size, err = io.Copy(file, r)
+	if err != nil {
+		body.Close()
+		return nil, bufferError(err)
+	}
+
+	return body, nil
+}
+
+// bufferError reports a body that exceeded the buffer limit as a RetryBodyTooLarge error, wrapping any other error in a CopyError
+func bufferError(err error) error {
+	if tooLarge, ok := err.(*ResponseTooLarge); ok {
+		return &RetryBodyTooLarge{tooLarge.limit, tooLarge.read}
+	}
+
+	return &CopyError{err}
+}
+
+// rewind sets a fresh copy of the body on the request
+func (b *replayBody) rewind(req *http.Request) error {
+	if b == nil {
+		return nil
+	}
+
+	switch {
+	case b.getBody != nil:
+		// the original body is still unread on the first attempt
+		if !b.sent {
+			b.sent = true
+			return nil
+		}
+
+		body, err := b.getBody()
+		if err != nil {
+			return &CopyError{err}
+		}
+
+		req.Body = body
+	case b.file != nil:
+		req.Body = io.NopCloser(io.NewSectionReader(b.file, 0, b.size))
+	default:
+		req.Body = io.NopCloser(bytes.NewReader(b.data))
+	}
+
+	return nil
+}
+
+// Close removes the temp file of a spilled body
+func (b *replayBody) Close() error {
+	if b == nil || b.file == nil {
+		return nil
+	}
+
+	b.file.Close()
+
+	return os.Remove(b.file.Name())
+}
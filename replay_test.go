@@ -0,0 +1,155 @@
+package httpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRetryBodySpill(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	spilled := func() []string {
+		matches, err := filepath.Glob(filepath.Join(dir, "httpc-body-*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return matches
+	}
+
+	const size = 1 << 20
+
+	var calls atomic.Int64
+	var sawFile atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if len(data) != size {
+			t.Errorf("expected a %d byte body, got %d", size, len(data))
+		}
+
+		if len(spilled()) > 0 {
+			sawFile.Store(true)
+		}
+
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		threshold int64
+		body      func() io.Reader
+		spill     bool
+	}{
+		// the MultiReader hides the concrete type, so http.NewRequest can't set GetBody
+		{"spilled to a temp file", 1024, func() io.Reader { return io.MultiReader(bytes.NewReader(make([]byte, size))) }, true},
+		{"buffered in memory", 0, func() io.Reader { return io.MultiReader(bytes.NewReader(make([]byte, size))) }, false},
+		{"replayed with GetBody", 1024, func() io.Reader { return bytes.NewReader(make([]byte, size)) }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+			sawFile.Store(false)
+
+			client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RetryEnabled: true, RetryBodySpillThreshold: tt.threshold})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := client.Put(context.Background(), "/upload", tt.body(), nil, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if calls.Load() != 2 {
+				t.Fatalf("expected the upload to be retried once, got %d calls", calls.Load())
+			}
+
+			if sawFile.Load() != tt.spill {
+				t.Fatalf("expected temp file created %t, got %t", tt.spill, sawFile.Load())
+			}
+
+			if remaining := spilled(); len(remaining) != 0 {
+				t.Fatalf("expected the temp file to be removed, found %v", remaining)
+			}
+		})
+	}
+}
+
+func TestRetryBodyTooLarge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		spill int64
+	}{
+		{"in memory", 0},
+		{"spilled", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := NewRetryTransport(http.DefaultTransport, 2, WithRetryBodyLimit(10), WithRetryBodySpillThreshold(tt.spill))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// a pipe can't be rewound, so the body has to be buffered for replay
+			pr, pw := io.Pipe()
+			go func() {
+				pw.Write(bytes.Repeat([]byte("x"), 50))
+				pw.Close()
+			}()
+
+			req, err := http.NewRequest(http.MethodPut, srv.URL, pr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = transport.RoundTrip(req)
+
+			var tooLarge *RetryBodyTooLarge
+			if !errors.As(err, &tooLarge) || tooLarge.Limit() != 10 || tooLarge.Size() != 11 {
+				t.Fatalf("expected a limit of 10 exceeded after 11 bytes, got %v", err)
+			}
+
+			req, err = http.NewRequest(http.MethodPut, srv.URL, io.NopCloser(strings.NewReader("small")))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("expected a body within the limit to be sent, got %v", err)
+			}
+			resp.Body.Close()
+		})
+	}
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RetryEnabled: true, RetryBodyLimit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Put(context.Background(), "/", io.NopCloser(strings.NewReader("toolong")), nil, nil)
+
+	var tooLarge *RetryBodyTooLarge
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("expected *RetryBodyTooLarge through the client, got %v", err)
+	}
+}
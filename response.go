@@ -0,0 +1,49 @@
+package httpc
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// replayedBody is a response body buffered with WithResponseBodyReplay. It can be read once as the response body and again through ReplayBody
+type replayedBody struct {
+	*bytes.Reader
+	data []byte
+}
+
+func (b *replayedBody) Close() error {
+	return nil
+}
+
+// ReplayBody returns a fresh reader over a response body buffered with WithResponseBodyReplay. It reports false when the body wasn't buffered
+func ReplayBody(resp *http.Response) (io.Reader, bool) {
+	if resp == nil {
+		return nil, false
+	}
+
+	body, ok := resp.Body.(*replayedBody)
+	if !ok {
+		return nil, false
+	}
+
+	return bytes.NewReader(body.data), true
+}
+
+// bufferBody reads the response body up to the maximum response size and replaces it with a replayable copy
+func (c *Client) bufferBody(resp *http.Response) error {
+	data, err := c.bodyPool.readAll(c.limitBody(resp.Body))
+	resp.Body.Close()
+
+	if err != nil {
+		if _, ok := err.(*ResponseTooLarge); !ok {
+			err = &RequestError{err}
+		}
+
+		return err
+	}
+
+	resp.Body = &replayedBody{Reader: bytes.NewReader(data), data: data}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package httpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReplayBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"a":3}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithResponseBodyReplay())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct{ A int }
+	resp, err := client.Get(context.Background(), "/", nil, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.A != 3 {
+		t.Fatalf("expected the body to be decoded, got %+v", decoded)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || string(data) != `{"a":3}` {
+		t.Fatalf("expected the body to be readable after decoding, got %q, %v", data, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		reader, ok := ReplayBody(resp)
+		if !ok {
+			t.Fatal("expected a replayable body")
+		}
+
+		if replayed, err := io.ReadAll(reader); err != nil || string(replayed) != `{"a":3}` {
+			t.Fatalf("replay %d: expected the full body, got %q, %v", i, replayed, err)
+		}
+	}
+
+	plain, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = plain.Get(context.Background(), "/", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := ReplayBody(resp); ok {
+		t.Fatal("expected replay to be off by default")
+	}
+}
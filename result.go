@@ -0,0 +1,102 @@
+package httpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+// RequestSpec bundles everything needed to make a request with Client.Request
+type RequestSpec struct {
+	Method   string
+	Resource string
+	Body     io.Reader
+	Headers  map[string]string
+	Decoded  interface{}
+}
+
+// Result holds the outcome of a request made with Client.Request
+type Result struct {
+	Response   *http.Response
+	Decoded    interface{}
+	StatusCode int
+	Err        error
+	body       []byte
+}
+
+// IsSuccess reports whether the request completed with a 2XX status code
+func (r Result) IsSuccess() bool {
+	return r.Err == nil && r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// BodyBytes returns the response body read during the request
+func (r Result) BodyBytes() []byte {
+	return r.body
+}
+
+// Request makes a request described by the supplied spec and returns a Result. The response body is read and closed before returning
+// and is available through Result.BodyBytes. If a decode target is supplied, a successful response body will be decoded into it
+func (c *Client) Request(ctx context.Context, spec RequestSpec) Result {
+	req, err := c.newRequest(ctx, spec.Method, spec.Resource, spec.Body, spec.Headers)
+	if err != nil {
+		return Result{Err: err}
+	}
+
+	resp, err := c.roundTrip(req)
+	if err != nil {
+		return Result{Err: &RequestError{err}}
+	}
+	defer resp.Body.Close()
+
+	return c.newResult(resp, spec.Decoded)
+}
+
+// newResult reads the response body and builds a Result from it, decoding a successful body into decoded when supplied
+func (c *Client) newResult(resp *http.Response, decoded interface{}) Result {
+	result := Result{
+		Response:   resp,
+		StatusCode: resp.StatusCode,
+	}
+
+	body, err := c.bodyPool.readAll(c.limitBody(resp.Body))
+	if err != nil {
+		if _, ok := err.(*ResponseTooLarge); !ok {
+			err = &RequestError{err}
+		}
+
+		result.Err = err
+		return result
+	}
+
+	result.body = body
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		result.Err = newBadStatusCode(resp, c.bodyPool)
+		return result
+	}
+
+	if err := c.checkRequiredHeaders(resp); err != nil {
+		result.Err = err
+		return result
+	}
+
+	if decoded != nil && len(body) > 0 {
+		decoder, ok := c.decoderFor(resp)
+		if !ok {
+			result.Err = unexpectedContentType(resp, bytes.NewReader(body))
+			return result
+		}
+
+		if err := c.unmarshal(body, decoder, decoded); err != nil {
+			result.Err = newDecodeError(err, body)
+			return result
+		}
+
+		result.Decoded = decoded
+	}
+
+	return result
+}
@@ -0,0 +1,47 @@
+package httpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestResult(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+			return
+		}
+
+		w.Write([]byte(`{"name":"gopher"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct{ Name string }
+	result := client.Request(context.Background(), RequestSpec{Method: http.MethodGet, Resource: "/ok", Decoded: &decoded})
+
+	if !result.IsSuccess() || result.Decoded == nil || decoded.Name != "gopher" {
+		t.Fatalf("expected a decoded success result, got %+v", result)
+	}
+
+	if string(result.BodyBytes()) != `{"name":"gopher"}` {
+		t.Fatalf("expected the raw body to be kept, got %q", result.BodyBytes())
+	}
+
+	result = client.Request(context.Background(), RequestSpec{Method: http.MethodGet, Resource: "/missing"})
+
+	if result.IsSuccess() || result.StatusCode != http.StatusNotFound || string(result.BodyBytes()) != "not found" {
+		t.Fatalf("expected a not found result, got %+v", result)
+	}
+
+	if _, ok := result.Err.(*BadStatusCode); !ok {
+		t.Fatalf("expected a BadStatusCode error, got %v", result.Err)
+	}
+}
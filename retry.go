@@ -2,23 +2,273 @@ package httpc
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"math"
+	"math/rand"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	DefaultRetryMax int = 3
+
+	DefaultRetryMaxDelay time.Duration = 30 * time.Second
+
+	// DefaultRetryPredicatePeekLimit is the number of response body bytes buffered for a retry predicate
+	DefaultRetryPredicatePeekLimit int64 = 64 * 1024
+
+	// retryBaseDelay is the delay before the first retry that the backoff strategies grow from
+	retryBaseDelay time.Duration = time.Second
+
+	RetryCountAttribute attribute.Key = "http.retry_count"
+)
+
+// BackoffStrategy selects how the delay between retries grows
+type BackoffStrategy int
+
+const (
+	// BackoffFullJitter picks a random delay between zero and a doubling delay
+	BackoffFullJitter BackoffStrategy = iota
+
+	// BackoffDecorrelatedJitter picks a random delay between the base delay and three times the previous delay
+	BackoffDecorrelatedJitter
+)
+
+// RetryAfterZeroPolicy controls how a Retry-After value of zero is interpreted
+type RetryAfterZeroPolicy int
+
+const (
+	// RetryAfterZeroBackoff falls back to the exponential backoff to avoid retrying in a tight loop
+	RetryAfterZeroBackoff RetryAfterZeroPolicy = iota
+
+	// RetryAfterZeroImmediate retries straight away
+	RetryAfterZeroImmediate
 )
 
+// defaultRetryableStatusCodes are retried when no status codes are configured
+var defaultRetryableStatusCodes = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
 type RetryTransport struct {
 	transport http.RoundTripper
 	retryMax  int
+
+	// recordRetries adds the retry count to the span found in the request context
+	recordRetries bool
+
+	retryOnTimeout bool
+	retryOn5xx     bool
+
+	// retryNonIdempotent allows POST and PATCH requests to be retried
+	retryNonIdempotent bool
+
+	// retryableCodes replaces the default set of retried status codes when neither retry condition is enabled
+	retryableCodes map[int]bool
+
+	// onRetry is called before each retry with the failed attempt
+	onRetry func(attempt int, req *http.Request, resp *http.Response, err error)
+
+	// predicate replaces the built in retry conditions when set
+	predicate func(req *http.Request, resp *http.Response, err error, attempt int) bool
+
+	// peekLimit caps the response body bytes buffered for a predicate set with WithRetryPredicate
+	peekLimit int64
+
+	// spillThreshold is the body size above which request bodies are buffered to a temp file instead of memory
+	spillThreshold int64
+
+	// bodyLimit is the largest request body that is buffered for replay when the request doesn't provide GetBody
+	bodyLimit int64
+
+	retryAfterZero RetryAfterZeroPolicy
+
+	maxDelay time.Duration
+	strategy BackoffStrategy
+
+	attemptTimeout time.Duration
+
+	// maxElapsed bounds the total time spent across attempts and backoff
+	maxElapsed time.Duration
+
+	// jitter randomizes the backoff delay, falling back to the shared math/rand source when nil
+	jitter   *rand.Rand
+	jitterMu sync.Mutex
+}
+
+type RetryOption func(t *RetryTransport)
+
+// WithRetryOnTimeout retries requests that fail with a connect or read timeout. When neither this nor WithRetryOn5xx is
+// enabled, every error, 429 and transient 5XX status code is retried
+func WithRetryOnTimeout(enabled bool) RetryOption {
+	return func(t *RetryTransport) {
+		t.retryOnTimeout = enabled
+	}
+}
+
+// WithRetryOn5xx retries requests that receive a 5XX status code. When neither this nor WithRetryOnTimeout is
+// enabled, every error, 429 and transient 5XX status code is retried
+func WithRetryOn5xx(enabled bool) RetryOption {
+	return func(t *RetryTransport) {
+		t.retryOn5xx = enabled
+	}
+}
+
+// WithRetryNonIdempotent retries POST and PATCH requests, which are otherwise sent once since a retry could repeat a write the server
+// already processed. Only enable it when those requests are safe to repeat, for example when they carry an idempotency key
+func WithRetryNonIdempotent(enabled bool) RetryOption {
+	return func(t *RetryTransport) {
+		t.retryNonIdempotent = enabled
+	}
+}
+
+// WithRetryableStatusCodes replaces the status codes retried when neither WithRetryOnTimeout nor WithRetryOn5xx is enabled. Connection
+// errors are always retried and an empty set keeps the default of 429, 500, 502, 503 and 504
+func WithRetryableStatusCodes(codes ...int) RetryOption {
+	return func(t *RetryTransport) {
+		t.retryableCodes = make(map[int]bool, len(codes))
+		for _, code := range codes {
+			t.retryableCodes[code] = true
+		}
+	}
+}
+
+// WithOnRetry calls fn before each retry backoff with the retry number, starting at 1, and the response or error of the attempt being
+// retried, allowing retries to be counted or logged. The response body must not be read
+func WithOnRetry(fn func(attempt int, req *http.Request, resp *http.Response, err error)) RetryOption {
+	return func(t *RetryTransport) {
+		t.onRetry = fn
+	}
+}
+
+// WithShouldRetry replaces the built in retry conditions with fn. It is called after every attempt with the number of attempts made so far,
+// starting at 1, and the retry limit still applies. POST and PATCH requests are still only retried when WithRetryNonIdempotent is enabled
+func WithShouldRetry(fn func(req *http.Request, resp *http.Response, err error, attempt int) bool) RetryOption {
+	return func(t *RetryTransport) {
+		t.predicate = fn
+	}
+}
+
+// WithRetryPredicate replaces the built in retry conditions with fn, like WithShouldRetry, taking precedence over it when both are set.
+// Up to the peek limit of the response body is buffered before fn is called, so fn may read it to decide, such as for an API that reports
+// throttling in a 200 response, and the whole body is restored for the next attempt or the caller. Responses opened for streaming, such as
+// with Stream or DownloadFile, are passed with an empty body so they aren't held up. A nil fn leaves the retry conditions unchanged
+func WithRetryPredicate(fn func(req *http.Request, resp *http.Response, err error) bool) RetryOption {
+	return func(t *RetryTransport) {
+		if fn == nil {
+			return
+		}
+
+		t.predicate = func(req *http.Request, resp *http.Response, err error, attempt int) bool {
+			if resp == nil || resp.Body == nil {
+				return fn(req, resp, err)
+			}
+
+			body := resp.Body
+			defer func() { resp.Body = body }()
+
+			if isStreaming(req) {
+				resp.Body = http.NoBody
+				return fn(req, resp, err)
+			}
+
+			peeked, restored := peekBody(body, t.peekLimit)
+			body = restored
+
+			resp.Body = io.NopCloser(bytes.NewReader(peeked))
+
+			return fn(req, resp, err)
+		}
+	}
+}
+
+// WithRetryPredicatePeekLimit caps the number of response body bytes buffered for the predicate set with WithRetryPredicate, defaulting
+// to DefaultRetryPredicatePeekLimit
+func WithRetryPredicatePeekLimit(limit int64) RetryOption {
+	return func(t *RetryTransport) {
+		if limit > 0 {
+			t.peekLimit = limit
+		}
+	}
+}
+
+// WithRetryBodySpillThreshold buffers request bodies larger than threshold bytes to a temp file so they can be replayed on retry
+// without being held in memory. A threshold of zero keeps every body in memory
+func WithRetryBodySpillThreshold(threshold int64) RetryOption {
+	return func(t *RetryTransport) {
+		t.spillThreshold = threshold
+	}
+}
+
+// WithRetryBodyLimit caps the size of request bodies buffered for replay. Requests without GetBody whose body exceeds the limit fail with
+// a RetryBodyTooLarge error. A limit of zero buffers bodies of any size
+func WithRetryBodyLimit(limit int64) RetryOption {
+	return func(t *RetryTransport) {
+		t.bodyLimit = limit
+	}
+}
+
+// WithRetryAfterZeroHandling sets how a Retry-After value of zero is interpreted. The default is RetryAfterZeroBackoff
+func WithRetryAfterZeroHandling(policy RetryAfterZeroPolicy) RetryOption {
+	return func(t *RetryTransport) {
+		t.retryAfterZero = policy
+	}
+}
+
+// WithRetryMaxDelay caps the backoff delay between retries. The default is DefaultRetryMaxDelay
+func WithRetryMaxDelay(delay time.Duration) RetryOption {
+	return func(t *RetryTransport) {
+		if delay > 0 {
+			t.maxDelay = delay
+		}
+	}
+}
+
+// WithRetryMaxElapsed stops retrying once the time since the first attempt, including the next backoff delay, would exceed the supplied
+// duration, returning the last attempt. The default of zero only limits the number of retries
+func WithRetryMaxElapsed(elapsed time.Duration) RetryOption {
+	return func(t *RetryTransport) {
+		t.maxElapsed = elapsed
+	}
+}
+
+// WithAttemptTimeout bounds each attempt, including reading its response body, by the supplied timeout. Attempts never run past the
+// request deadline, and no further attempt is made once backing off would reach it
+func WithAttemptTimeout(timeout time.Duration) RetryOption {
+	return func(t *RetryTransport) {
+		t.attemptTimeout = timeout
+	}
+}
+
+// WithBackoffStrategy selects how the delay between retries grows. The default is BackoffFullJitter
+func WithBackoffStrategy(strategy BackoffStrategy) RetryOption {
+	return func(t *RetryTransport) {
+		t.strategy = strategy
+	}
+}
+
+// WithRetryJitterSource sets the random source used to jitter the backoff delay, allowing a deterministic source to be supplied
+func WithRetryJitterSource(src rand.Source) RetryOption {
+	return func(t *RetryTransport) {
+		t.jitter = rand.New(src)
+	}
 }
 
 // NewRetryTransport wraps the supplied http transport with a retryable implementation
-func NewRetryTransport(transport *http.Transport, maxRetry int) (*RetryTransport, error) {
+func NewRetryTransport(transport http.RoundTripper, maxRetry int, opts ...RetryOption) (*RetryTransport, error) {
 	var retryCount int
 	retryCount = DefaultRetryMax
 
@@ -26,64 +276,272 @@ func NewRetryTransport(transport *http.Transport, maxRetry int) (*RetryTransport
 		retryCount = maxRetry
 	}
 
-	return &RetryTransport{
+	retryTransport := &RetryTransport{
 		transport: transport,
 		retryMax:  retryCount,
-	}, nil
+		maxDelay:  DefaultRetryMaxDelay,
+		peekLimit: DefaultRetryPredicatePeekLimit,
+	}
+
+	for _, opt := range opts {
+		opt(retryTransport)
+	}
+
+	return retryTransport, nil
 }
 
 // RoundTrip implements the http.RoundTripper interface with retries
 func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	var bodyBytes []byte
-	var err error
+	// non idempotent requests are sent once without buffering their body
+	if !t.retryable(req) {
+		return t.transport.RoundTrip(req)
+	}
 
-	if req.Body != nil {
-		bodyBytes, err := io.ReadAll(req.Body)
-		if err != nil {
-			return nil, &CopyError{err}
-		}
+	body, err := t.replayableBody(req)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
 
-		req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+	if err := body.rewind(req); err != nil {
+		return nil, err
 	}
 
-	resp, err := t.transport.RoundTrip(req)
+	start := time.Now()
+	resp, err := t.attempt(req, 0)
+
+	backoff := t.newBackoff()
 
 	retries := 0
-	for shouldRetry(resp, err) && retries < t.retryMax {
-		time.Sleep(backoff(retries))
+	for retries < t.retryMax && !isCircuitOpen(err) && t.shouldRetry(req, resp, err, retries+1) {
+		delay := backoff(retries)
+		if retryAfter, ok := parseRetryAfter(resp, time.Now()); ok && (retryAfter > 0 || t.retryAfterZero == RetryAfterZeroImmediate) {
+			delay = retryAfter
+		}
+
+		// return the last attempt rather than backing off past the deadline, leaving no time for another attempt
+		if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) <= delay {
+			break
+		}
+
+		if t.exceedsElapsed(start, delay) {
+			break
+		}
+
+		if t.onRetry != nil {
+			t.onRetry(retries+1, req, resp, err)
+		}
+
+		if sleepCtx(req.Context(), delay) != nil || t.exceedsElapsed(start, 0) {
+			break
+		}
 
 		// discard response body to reuse connection
-		if resp.Body != nil {
+		if resp != nil && resp.Body != nil {
 			io.Copy(io.Discard, resp.Body)
 			resp.Body.Close()
 		}
 
-		if req.Body != nil {
-			req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+		if err := body.rewind(req); err != nil {
+			return nil, err
 		}
 
-		resp, err = t.transport.RoundTrip(req)
+		resp, err = t.attempt(req, retries+1)
 
 		retries++
 	}
 
+	if t.recordRetries {
+		trace.SpanFromContext(req.Context()).SetAttributes(RetryCountAttribute.Int(retries))
+	}
+
 	return resp, err
 }
 
-// shouldRetry checks for errors and non 2XX status codes to determine whether to retry
-func shouldRetry(resp *http.Response, err error) bool {
+// exceedsElapsed reports whether waiting for the delay would take the time since the first attempt past the max elapsed time
+func (t *RetryTransport) exceedsElapsed(start time.Time, delay time.Duration) bool {
+	return t.maxElapsed > 0 && time.Since(start)+delay >= t.maxElapsed
+}
+
+// attempt sends the request once, bounded by the attempt timeout when set. The attempt context derives from the request context,
+// so its timeout never extends past the time remaining before the request deadline after backing off
+func (t *RetryTransport) attempt(req *http.Request, retry int) (*http.Response, error) {
+	if retry > 0 {
+		req = req.WithContext(context.WithValue(req.Context(), retryAttemptKey{}, retry))
+	}
+
+	if t.attemptTimeout <= 0 {
+		return t.transport.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.attemptTimeout)
+
+	resp, err := t.transport.RoundTrip(req.WithContext(ctx))
 	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = &cancelBody{resp.Body, cancel}
+
+	return resp, nil
+}
+
+// retryAttemptKey holds the retry number of an attempt in its request context
+type retryAttemptKey struct{}
+
+// RetryAttempt returns the retry number of a request seen by a transport beneath the retry layer, such as middleware added with
+// WithTransportMiddleware. It is zero for the first attempt and one for the first retry
+func RetryAttempt(req *http.Request) int {
+	retry, _ := req.Context().Value(retryAttemptKey{}).(int)
+	return retry
+}
+
+// retryable reports whether the request method may be retried. POST and PATCH requests are only retried when non idempotent retries
+// are enabled or the request was made with PostIdempotent, a custom predicate only decides for requests that may be retried
+func (t *RetryTransport) retryable(req *http.Request) bool {
+	if t.retryNonIdempotent {
 		return true
 	}
 
-	if resp.StatusCode/10 != 20 {
+	if idempotent, _ := req.Context().Value(idempotentKey{}).(bool); idempotent {
+		return true
+	}
+
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions, http.MethodTrace:
 		return true
 	}
 
 	return false
 }
 
-// backoff doubles the delay
-func backoff(retries int) time.Duration {
-	return time.Duration(math.Pow(2, float64(retries))) * time.Second
+// hasBody reports whether the request carries a body that needs to be replayed. Bodyless requests
+// are left untouched so the transport doesn't send a spurious empty body
+func hasBody(req *http.Request) bool {
+	return req.Body != nil && req.Body != http.NoBody
+}
+
+// shouldRetry defers to the custom predicate when set, otherwise it combines the enabled retry conditions, falling back to retrying
+// every error, 429 and transient 5XX status code when none are enabled
+func (t *RetryTransport) shouldRetry(req *http.Request, resp *http.Response, err error, attempt int) bool {
+	if t.predicate != nil {
+		return t.predicate(req, resp, err, attempt)
+	}
+
+	if !t.retryOnTimeout && !t.retryOn5xx {
+		return shouldRetry(resp, err, t.retryableCodes)
+	}
+
+	if err != nil {
+		return t.retryOnTimeout && isTimeout(err)
+	}
+
+	return t.retryOn5xx && resp.StatusCode >= 500
+}
+
+// isCircuitOpen checks whether the attempt was rejected by an open circuit breaker, which retrying can't help
+func isCircuitOpen(err error) bool {
+	var circuitErr *CircuitOpen
+	return errors.As(err, &circuitErr)
+}
+
+// isTimeout checks whether the error was caused by a connect or read timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// shouldRetry retries connection errors and the supplied status codes, which default to rate limiting and transient server errors.
+// Other 4XX responses will never succeed on retry and are returned as is
+func shouldRetry(resp *http.Response, err error, codes map[int]bool) bool {
+	if err != nil {
+		return true
+	}
+
+	if len(codes) == 0 {
+		codes = defaultRetryableStatusCodes
+	}
+
+	return codes[resp.StatusCode]
+}
+
+// peekBody reads up to limit bytes of the body, returning them along with a body that yields the peeked bytes followed by the rest
+func peekBody(body io.ReadCloser, limit int64) ([]byte, io.ReadCloser) {
+	peeked, _ := io.ReadAll(io.LimitReader(body, limit))
+
+	return peeked, &peekedBody{io.MultiReader(bytes.NewReader(peeked), body), body}
+}
+
+// peekedBody reads a response body whose start was peeked, closing the original body
+type peekedBody struct {
+	io.Reader
+	body io.Closer
+}
+
+func (b *peekedBody) Close() error {
+	return b.body.Close()
+}
+
+// parseRetryAfter reads the Retry-After header in either its delta-seconds or HTTP-date form. It reports false when the header is absent or malformed
+func parseRetryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	header := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(date.Sub(now), 0), true
+}
+
+// newBackoff returns the backoff for a single RoundTrip call, so strategies that depend on the previous delay don't share state across requests
+func (t *RetryTransport) newBackoff() func(retries int) time.Duration {
+	if t.strategy != BackoffDecorrelatedJitter {
+		return t.backoff
+	}
+
+	prev := retryBaseDelay
+
+	return func(retries int) time.Duration {
+		prev = min(t.maxDelay, retryBaseDelay+t.random(max(prev*3-retryBaseDelay, 0)))
+		return prev
+	}
+}
+
+// backoff picks a random delay between zero and the doubling delay for the supplied retry, capped at the max delay, so clients don't retry in lockstep
+func (t *RetryTransport) backoff(retries int) time.Duration {
+	delay := t.maxDelay
+	if exp := math.Pow(2, float64(retries)) * float64(retryBaseDelay); exp < float64(delay) {
+		delay = time.Duration(exp)
+	}
+
+	return t.random(delay)
+}
+
+// random returns a random duration between zero and n inclusive from the jitter source
+func (t *RetryTransport) random(n time.Duration) time.Duration {
+	if t.jitter == nil {
+		return time.Duration(rand.Int63n(int64(n) + 1))
+	}
+
+	t.jitterMu.Lock()
+	defer t.jitterMu.Unlock()
+
+	return time.Duration(t.jitter.Int63n(int64(n) + 1))
 }
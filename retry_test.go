@@ -0,0 +1,757 @@
+package httpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPredicate(t *testing.T) {
+	var attempts atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Write([]byte(`{"error":"throttled"}`))
+			return
+		}
+
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	cfg := &Config{
+		BaseUrl:       srv.URL,
+		RetryEnabled:  true,
+		RetryMax:      2,
+		RetryMaxDelay: time.Millisecond,
+		RetryPredicate: func(req *http.Request, resp *http.Response, err error) bool {
+			if err != nil {
+				return true
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			return strings.Contains(string(body), "throttled")
+		},
+	}
+
+	client, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct{ Ok bool }
+	if _, err := client.Get(context.Background(), "/", nil, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if !decoded.Ok || attempts.Load() != 2 {
+		t.Fatalf("expected the second attempt to be decoded, got %+v after %d attempts", decoded, attempts.Load())
+	}
+}
+
+func TestRetryPredicatePeekLimit(t *testing.T) {
+	body := strings.Repeat("a", 100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	var peeked int
+	predicate := func(req *http.Request, resp *http.Response, err error) bool {
+		data, _ := io.ReadAll(resp.Body)
+		peeked = len(data)
+		return false
+	}
+
+	retryTransport, err := NewRetryTransport(http.DefaultTransport, 1, WithRetryPredicate(predicate), WithRetryPredicatePeekLimit(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := retryTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if peeked != 10 {
+		t.Fatalf("expected the predicate to see 10 bytes, got %d", peeked)
+	}
+
+	if string(got) != body {
+		t.Fatalf("expected the whole body to be restored, got %d bytes", len(got))
+	}
+}
+
+func TestRetryPredicateStream(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: hello\n\n"))
+		w.(http.Flusher).Flush()
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	cfg := &Config{
+		BaseUrl:        srv.URL,
+		RetryEnabled:   true,
+		RetryPredicate: func(req *http.Request, resp *http.Response, err error) bool { return err != nil },
+	}
+
+	client, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	events, _, err := client.StreamEvents(ctx, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-events:
+		if event.Data != "hello" {
+			t.Fatalf("unexpected event %+v", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("stream was held up by the retry predicate")
+	}
+}
+
+func TestDeleteWithoutBody(t *testing.T) {
+	var contentLength int64
+	var transferEncoding []string
+	var header http.Header
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentLength = r.ContentLength
+		transferEncoding = r.TransferEncoding
+		header = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	for _, retry := range []bool{false, true} {
+		client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, RetryEnabled: retry})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, body := range []io.Reader{nil, bytes.NewReader(nil)} {
+			if _, err := client.Delete(context.Background(), "/x", body, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if header.Get("Content-Length") != "" || len(transferEncoding) != 0 || contentLength != 0 {
+				t.Fatalf("expected a bodyless DELETE with retries %v, got Content-Length %q and Transfer-Encoding %v", retry, header.Get("Content-Length"), transferEncoding)
+			}
+		}
+	}
+}
+
+// timeoutError is a net.Error reporting a timeout
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// roundTripFunc adapts a function to the http.RoundTripper interface
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryToggles(t *testing.T) {
+	var calls atomic.Int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	timingOut := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		return nil, timeoutError{}
+	})
+
+	tests := []struct {
+		name      string
+		transport http.RoundTripper
+		opts      []RetryOption
+		want      int64
+	}{
+		{"timeouts retried", timingOut, []RetryOption{WithRetryOnTimeout(true), WithRetryOn5xx(false)}, 2},
+		{"5xx not retried", http.DefaultTransport, []RetryOption{WithRetryOnTimeout(true), WithRetryOn5xx(false)}, 1},
+		{"timeouts not retried", timingOut, []RetryOption{WithRetryOnTimeout(false), WithRetryOn5xx(true)}, 1},
+		{"5xx retried", http.DefaultTransport, []RetryOption{WithRetryOnTimeout(false), WithRetryOn5xx(true)}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+
+			transport, err := NewRetryTransport(tt.transport, 1, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := &http.Client{Transport: transport, Timeout: 5 * time.Second}
+
+			resp, err := client.Do(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+
+			if got := calls.Load(); got != tt.want {
+				t.Fatalf("expected %d calls, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	cfg := &Config{
+		BaseUrl:       srv.URL,
+		RetryEnabled:  true,
+		RetryMax:      5,
+		RetryMaxDelay: 10 * time.Millisecond,
+		ShouldRetry: func(req *http.Request, resp *http.Response, err error, attempt int) bool {
+			return attempt <= 2
+		},
+	}
+
+	client, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls.Load() != 3 {
+		t.Fatalf("expected a successful response to be retried exactly twice, got %d calls", calls.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"delta seconds", "3", 3 * time.Second, true},
+		{"http date", now.Add(5 * time.Second).Format(http.TimeFormat), 5 * time.Second, true},
+		{"malformed", "soon", 0, false},
+		{"negative", "-1", 0, false},
+		{"absent", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.header != "" {
+				resp.Header.Set("Retry-After", tt.header)
+			}
+
+			got, ok := parseRetryAfter(resp, now)
+			if got != tt.want || ok != tt.ok {
+				t.Fatalf("expected (%s, %t), got (%s, %t)", tt.want, tt.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestRetryAfterHonored(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}))
+	defer srv.Close()
+
+	transport, err := NewRetryTransport(http.DefaultTransport, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	resp, err := (&http.Client{Transport: transport}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the retry to succeed, got %d", resp.StatusCode)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("expected the retry to wait for the Retry-After delay, took %s", elapsed)
+	}
+}
+
+func TestShouldRetryClassification(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *http.Response
+		err  error
+		want bool
+	}{
+		{"400", &http.Response{StatusCode: http.StatusBadRequest}, nil, false},
+		{"401", &http.Response{StatusCode: http.StatusUnauthorized}, nil, false},
+		{"404", &http.Response{StatusCode: http.StatusNotFound}, nil, false},
+		{"429", &http.Response{StatusCode: http.StatusTooManyRequests}, nil, true},
+		{"500", &http.Response{StatusCode: http.StatusInternalServerError}, nil, true},
+		{"501", &http.Response{StatusCode: http.StatusNotImplemented}, nil, false},
+		{"502", &http.Response{StatusCode: http.StatusBadGateway}, nil, true},
+		{"503", &http.Response{StatusCode: http.StatusServiceUnavailable}, nil, true},
+		{"504", &http.Response{StatusCode: http.StatusGatewayTimeout}, nil, true},
+		{"transport error", nil, io.ErrUnexpectedEOF, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldRetry(tt.resp, tt.err, nil); got != tt.want {
+				t.Fatalf("expected shouldRetry to return %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRetryAfterZeroHandling(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1)%2 == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}))
+	defer srv.Close()
+
+	// transports seeded alike pick the same jitter, so the reference predicts the backoff for the first retry
+	const seed = 1
+
+	reference, err := NewRetryTransport(http.DefaultTransport, 1, WithRetryJitterSource(rand.NewSource(seed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	backoff := reference.backoff(0)
+
+	tests := []struct {
+		name    string
+		policy  RetryAfterZeroPolicy
+		backoff bool
+	}{
+		{"backoff", RetryAfterZeroBackoff, true},
+		{"immediate", RetryAfterZeroImmediate, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := NewRetryTransport(http.DefaultTransport, 1, WithRetryJitterSource(rand.NewSource(seed)), WithRetryAfterZeroHandling(tt.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+
+			resp, err := (&http.Client{Transport: transport}).Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			elapsed := time.Since(start)
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected the retry to succeed, got %d", resp.StatusCode)
+			}
+
+			if tt.backoff && elapsed < backoff {
+				t.Fatalf("expected to back off for %s, took %s", backoff, elapsed)
+			}
+
+			if !tt.backoff && elapsed >= backoff {
+				t.Fatalf("expected to retry immediately rather than back off for %s, took %s", backoff, elapsed)
+			}
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	const maxDelay = 5 * time.Second
+
+	transport, err := NewRetryTransport(http.DefaultTransport, 3, WithRetryJitterSource(rand.NewSource(1)), WithRetryMaxDelay(maxDelay))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := NewRetryTransport(http.DefaultTransport, 3, WithRetryJitterSource(rand.NewSource(1)), WithRetryMaxDelay(maxDelay))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for retries := 0; retries < 70; retries++ {
+		limit := maxDelay
+		if exp := time.Duration(1<<retries) * retryBaseDelay; retries < 10 && exp < limit {
+			limit = exp
+		}
+
+		delay := transport.backoff(retries)
+		if delay < 0 || delay > limit {
+			t.Fatalf("retry %d: expected a delay between 0 and %s, got %s", retries, limit, delay)
+		}
+
+		if replayed := replay.backoff(retries); replayed != delay {
+			t.Fatalf("retry %d: expected the seeded jitter to be deterministic, got %s and %s", retries, delay, replayed)
+		}
+	}
+
+	defaults, err := NewRetryTransport(http.DefaultTransport, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if defaults.maxDelay != DefaultRetryMaxDelay {
+		t.Fatalf("expected the max delay to default to %s, got %s", DefaultRetryMaxDelay, defaults.maxDelay)
+	}
+
+	if delay := defaults.backoff(100); delay > DefaultRetryMaxDelay {
+		t.Fatalf("expected the delay to be capped at %s, got %s", DefaultRetryMaxDelay, delay)
+	}
+}
+
+func TestRetryableStatusCodes(t *testing.T) {
+	tests := []struct {
+		name  string
+		codes []int
+		calls int64
+		fails bool
+	}{
+		{"included", []int{http.StatusConflict, http.StatusTooEarly}, 2, false},
+		{"excluded", []int{http.StatusTooEarly}, 1, true},
+		{"defaults", nil, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					w.WriteHeader(http.StatusConflict)
+				}
+			}))
+			defer srv.Close()
+
+			cfg := &Config{
+				BaseUrl:              srv.URL,
+				RetryEnabled:         true,
+				RetryMax:             2,
+				RetryMaxDelay:        10 * time.Millisecond,
+				RetryableStatusCodes: tt.codes,
+			}
+
+			client, err := NewClient(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = client.Get(context.Background(), "/", nil, nil)
+			if (err != nil) != tt.fails {
+				t.Fatalf("expected failure %t, got %v", tt.fails, err)
+			}
+
+			if calls.Load() != tt.calls {
+				t.Fatalf("expected %d calls, got %d", tt.calls, calls.Load())
+			}
+		})
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		method        string
+		nonIdempotent bool
+		predicate     bool
+		want          int64
+	}{
+		{http.MethodPost, false, false, 1},
+		{http.MethodPost, true, false, 3},
+		{http.MethodPatch, false, false, 1},
+		{http.MethodGet, false, false, 3},
+		{http.MethodGet, true, false, 3},
+		{http.MethodPost, false, true, 1},
+		{http.MethodPost, true, true, 3},
+		{http.MethodGet, false, true, 3},
+	}
+
+	for _, tt := range tests {
+		calls.Store(0)
+
+		opts := []RetryOption{WithRetryNonIdempotent(tt.nonIdempotent), WithRetryMaxDelay(time.Millisecond)}
+		if tt.predicate {
+			opts = append(opts, WithShouldRetry(func(req *http.Request, resp *http.Response, err error, attempt int) bool { return true }))
+		}
+
+		transport, err := NewRetryTransport(http.DefaultTransport, 2, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(tt.method, srv.URL, strings.NewReader("body"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if calls.Load() != tt.want {
+			t.Fatalf("%s with non idempotent retries %t and predicate %t: expected %d calls, got %d", tt.method, tt.nonIdempotent, tt.predicate, tt.want, calls.Load())
+		}
+	}
+}
+
+func TestDecorrelatedJitter(t *testing.T) {
+	const maxDelay = 20 * time.Second
+
+	transport, err := NewRetryTransport(http.DefaultTransport, 3, WithBackoffStrategy(BackoffDecorrelatedJitter), WithRetryJitterSource(rand.NewSource(7)), WithRetryMaxDelay(maxDelay))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for run := 0; run < 50; run++ {
+		backoff := transport.newBackoff()
+
+		prev := retryBaseDelay
+		for retries := 0; retries < 10; retries++ {
+			delay := backoff(retries)
+			if delay < retryBaseDelay || delay > min(maxDelay, prev*3) {
+				t.Fatalf("retry %d: expected a delay between %s and %s, got %s", retries, retryBaseDelay, min(maxDelay, prev*3), delay)
+			}
+
+			prev = delay
+		}
+	}
+
+	// each RoundTrip gets its own backoff, so one request's history doesn't widen another's first delay
+	first, second := transport.newBackoff(), transport.newBackoff()
+	for retries := 0; retries < 5; retries++ {
+		first(retries)
+	}
+
+	if delay := second(0); delay > 3*retryBaseDelay {
+		t.Fatalf("expected a fresh backoff to start from the base delay, got %s", delay)
+	}
+}
+
+func TestRetryDeadlineBudget(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			time.Sleep(300 * time.Millisecond)
+		}
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	transport, err := NewRetryTransport(http.DefaultTransport, 5, WithAttemptTimeout(100*time.Millisecond), WithRetryMaxDelay(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	if resp, err := transport.RoundTrip(req); err == nil {
+		resp.Body.Close()
+	}
+
+	if elapsed := time.Since(start); elapsed > 420*time.Millisecond {
+		t.Fatalf("expected the retries to stay within the 400ms deadline, took %s", elapsed)
+	}
+}
+
+func TestRetryDelayPastDeadline(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	transport, err := NewRetryTransport(http.DefaultTransport, 3, WithRetryMaxDelay(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 1 {
+		t.Fatalf("expected the last response to be returned without retrying, got %d after %d calls", resp.StatusCode, calls.Load())
+	}
+
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("expected a delay past the deadline to return immediately, took %s", elapsed)
+	}
+}
+
+func TestOnRetry(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	var attempts, statuses []int
+
+	cfg := &Config{
+		BaseUrl:       srv.URL,
+		RetryEnabled:  true,
+		RetryMaxDelay: time.Millisecond,
+		OnRetry: func(attempt int, req *http.Request, resp *http.Response, err error) {
+			attempts = append(attempts, attempt)
+			statuses = append(statuses, resp.StatusCode)
+		},
+	}
+
+	client, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Fatalf("expected OnRetry for attempts 1 and 2, got %v", attempts)
+	}
+
+	if statuses[0] != http.StatusServiceUnavailable || statuses[1] != http.StatusTooManyRequests {
+		t.Fatalf("expected the retry reasons 503 and 429, got %v", statuses)
+	}
+}
+
+func TestRetryMaxElapsed(t *testing.T) {
+	var attempts atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	transport, err := NewRetryTransport(http.DefaultTransport, 50, WithRetryMaxDelay(20*time.Millisecond), WithRetryMaxElapsed(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected the last response to be returned, got status %d", resp.StatusCode)
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected retries to stop after the elapsed budget, took %v", elapsed)
+	}
+
+	if n := attempts.Load(); n < 2 || n >= 50 {
+		t.Fatalf("expected the budget to cut retries short, got %d attempts", n)
+	}
+}
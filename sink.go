@@ -0,0 +1,65 @@
+package httpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// BodySink consumes the body of a successful response. The body is capped at the client's maximum response size and is closed once Consume returns
+type BodySink interface {
+	Consume(resp *http.Response) error
+}
+
+// JSONSink decodes the response body into the supplied struct pointer
+func JSONSink(decoded interface{}) BodySink {
+	return &jsonSink{decoded}
+}
+
+// DiscardSink drains the response body so the connection can be reused
+func DiscardSink() BodySink {
+	return discardSink{}
+}
+
+type jsonSink struct {
+	decoded interface{}
+}
+
+func (s *jsonSink) Consume(resp *http.Response) error {
+	if err := json.NewDecoder(resp.Body).Decode(s.decoded); err != nil {
+		if tooLarge, ok := err.(*ResponseTooLarge); ok {
+			return tooLarge
+		}
+
+		return &DecodeError{err: err}
+	}
+
+	return nil
+}
+
+type discardSink struct{}
+
+func (discardSink) Consume(resp *http.Response) error {
+	_, err := io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+// DoWithSink makes a request to the supplied endpoint and hands a successful response to sink. Non 2XX responses are returned as a
+// BadStatusCode error without calling the sink and errors returned by the sink are returned as is
+func (c *Client) DoWithSink(ctx context.Context, method string, resource string, body io.Reader, headers map[string]string, sink BodySink) (*http.Response, error) {
+	resp, err := c.open(ctx, method, resource, body, headers)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	limited := *resp
+	limited.Body = io.NopCloser(c.limitBody(resp.Body))
+
+	if err := sink.Consume(&limited); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
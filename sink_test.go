@@ -0,0 +1,77 @@
+package httpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// checksumSink records the hex encoded SHA-256 of the body it consumes
+type checksumSink struct{ sum string }
+
+func (s *checksumSink) Consume(resp *http.Response) error {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return err
+	}
+
+	s.sum = hex.EncodeToString(hash.Sum(nil))
+
+	return nil
+}
+
+func TestDoWithSink(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Write([]byte(`{"a":1}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &checksumSink{}
+	if _, err := client.DoWithSink(context.Background(), http.MethodGet, "/", nil, nil, sink); err != nil {
+		t.Fatal(err)
+	}
+
+	want := sha256.Sum256([]byte(`{"a":1}`))
+	if sink.sum != hex.EncodeToString(want[:]) {
+		t.Fatalf("unexpected checksum %s", sink.sum)
+	}
+
+	var decoded struct{ A int }
+	if _, err := client.DoWithSink(context.Background(), http.MethodGet, "/", nil, nil, JSONSink(&decoded)); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.A != 1 {
+		t.Fatalf("expected the JSON sink to decode the body, got %+v", decoded)
+	}
+
+	var statusErr *BadStatusCode
+	if _, err := client.DoWithSink(context.Background(), http.MethodGet, "/missing", nil, nil, DiscardSink()); !errors.As(err, &statusErr) {
+		t.Fatalf("expected a status error before the sink runs, got %v", err)
+	}
+
+	limited, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL}, WithMaxResponseBytes(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tooLarge *ResponseTooLarge
+	if _, err := limited.DoWithSink(context.Background(), http.MethodGet, "/", nil, nil, JSONSink(&decoded)); !errors.As(err, &tooLarge) {
+		t.Fatalf("expected the sink to see the limited body, got %v", err)
+	}
+}
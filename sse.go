@@ -0,0 +1,271 @@
+package httpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultEventRetry time.Duration = 3 * time.Second
+
+// Event is a single Server-Sent Event. Event defaults to message when the server doesn't name the event type
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// eventStream tracks the state shared across reconnections of an event stream
+type eventStream struct {
+	lastEventID string
+	retry       time.Duration
+}
+
+// SubscribeEvents consumes the Server-Sent Events stream at the supplied endpoint, calling handle for every event. Dropped connections
+// are reopened with the Last-Event-ID header set to the last event ID received, waiting for the delay set by the server's retry field,
+// or DefaultEventRetry, which doubles while reconnects keep failing. It returns when the context is done, the server responds with
+// 204 No Content or a non 2XX status code, or handle returns an error, which is returned as is
+func (c *Client) SubscribeEvents(ctx context.Context, resource string, headers map[string]string, handle func(event Event) error) error {
+	ctx = streamContext(ctx)
+
+	stream := &eventStream{retry: DefaultEventRetry}
+
+	failures := 0
+	for {
+		received, err := c.consumeEvents(ctx, stream, resource, headers, handle)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var handlerErr *eventHandlerError
+		if errors.As(err, &handlerErr) {
+			return handlerErr.err
+		}
+
+		if errors.Is(err, errStreamClosed) {
+			return nil
+		}
+
+		// reconnecting won't help when the server rejects the stream or it grows past the response size limit
+		var badStatus *BadStatusCode
+		var tooLarge *ResponseTooLarge
+		if errors.As(err, &badStatus) || errors.As(err, &tooLarge) {
+			return err
+		}
+
+		if received {
+			failures = 0
+		} else {
+			failures++
+		}
+
+		if err := sleepCtx(ctx, reconnectDelay(stream.retry, failures)); err != nil {
+			return err
+		}
+	}
+}
+
+// errStreamClosed is returned when the server responds with 204 No Content to signal the client should stop reconnecting
+var errStreamClosed = errors.New("event stream closed by server")
+
+// eventHandlerError marks an error returned by the event handler so it isn't mistaken for a dropped connection
+type eventHandlerError struct {
+	err error
+}
+
+func (e *eventHandlerError) Error() string {
+	return e.err.Error()
+}
+
+// StreamEvents opens a single connection to the Server-Sent Events stream at the supplied endpoint and sends its events on the returned
+// channel, which is closed when the stream ends, the server sends a [DONE] data event or the context is done. Unlike SubscribeEvents,
+// dropped connections aren't reopened. An error ending the stream early, including the context error, is sent on the error channel
+// before both channels are closed. Errors opening the stream are returned directly
+func (c *Client) StreamEvents(ctx context.Context, resource string, headers map[string]string) (<-chan Event, <-chan error, error) {
+	ctx = streamContext(ctx)
+
+	stream := &eventStream{retry: DefaultEventRetry}
+
+	resp, err := c.openEvents(ctx, stream, resource, headers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events := make(chan Event)
+	errs := make(chan error, 1)
+
+	// unblock a read on a body that won't close promptly once the context is done
+	stop := context.AfterFunc(ctx, func() {
+		resp.Body.Close()
+	})
+
+	go func() {
+		defer close(errs)
+		defer close(events)
+		defer stop()
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNoContent {
+			return
+		}
+
+		err := stream.read(c.limitBody(resp.Body), func(event Event) error {
+			if event.Data == "[DONE]" {
+				return errStreamClosed
+			}
+
+			select {
+			case events <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+
+		if err != nil && err != io.EOF && !errors.Is(err, errStreamClosed) {
+			errs <- err
+		}
+	}()
+
+	return events, errs, nil
+}
+
+// openEvents opens a connection to the event stream, resuming from the last event ID received
+func (c *Client) openEvents(ctx context.Context, stream *eventStream, resource string, headers map[string]string) (*http.Response, error) {
+	reqHeaders := map[string]string{
+		"Accept":        "text/event-stream",
+		"Cache-Control": "no-cache",
+	}
+
+	for key, val := range headers {
+		reqHeaders[key] = val
+	}
+
+	if stream.lastEventID != "" {
+		reqHeaders["Last-Event-ID"] = stream.lastEventID
+	}
+
+	return c.open(ctx, http.MethodGet, resource, nil, reqHeaders)
+}
+
+// consumeEvents opens a single connection to the event stream and dispatches its events until the body ends, reporting whether any event was received
+func (c *Client) consumeEvents(ctx context.Context, stream *eventStream, resource string, headers map[string]string, handle func(event Event) error) (bool, error) {
+	resp, err := c.openEvents(ctx, stream, resource, headers)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return false, errStreamClosed
+	}
+
+	received := false
+	err = stream.read(c.limitBody(resp.Body), func(event Event) error {
+		received = true
+
+		if err := handle(event); err != nil {
+			return &eventHandlerError{err}
+		}
+
+		return nil
+	})
+
+	return received, err
+}
+
+// read parses events from the body, dispatching each complete event. An event still being built when the body ends is discarded
+func (s *eventStream) read(body io.Reader, dispatch func(event Event) error) error {
+	reader := bufio.NewReader(body)
+
+	var data strings.Builder
+	var eventType string
+	hasData := false
+
+	// the id is only committed once its event is complete, so a partial event isn't skipped on reconnect
+	eventID := s.lastEventID
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if line == "" {
+			s.lastEventID = eventID
+
+			if hasData {
+				event := Event{
+					ID:    s.lastEventID,
+					Event: eventType,
+					Data:  strings.TrimSuffix(data.String(), "\n"),
+				}
+
+				if event.Event == "" {
+					event.Event = "message"
+				}
+
+				if err := dispatch(event); err != nil {
+					return err
+				}
+			}
+
+			data.Reset()
+			eventType = ""
+			hasData = false
+
+			continue
+		}
+
+		// lines starting with a colon are comments
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data.WriteString(value)
+			data.WriteString("\n")
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				eventID = value
+			}
+		case "retry":
+			if millis, err := strconv.ParseInt(value, 10, 64); err == nil && millis >= 0 {
+				s.retry = time.Duration(millis) * time.Millisecond
+			}
+		}
+	}
+}
+
+// reconnectDelay doubles the retry delay for every consecutive failed reconnect, capped at the default retry max delay
+func reconnectDelay(retry time.Duration, failures int) time.Duration {
+	if failures <= 1 {
+		return retry
+	}
+
+	delay := float64(retry) * math.Pow(2, float64(failures-1))
+	if delay > float64(DefaultRetryMaxDelay) {
+		return max(retry, DefaultRetryMaxDelay)
+	}
+
+	return time.Duration(delay)
+}
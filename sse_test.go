@@ -0,0 +1,226 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubscribeEventsReconnect(t *testing.T) {
+	var mu sync.Mutex
+	var lastEventIds []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		lastEventIds = append(lastEventIds, r.Header.Get("Last-Event-ID"))
+		conn := len(lastEventIds)
+		mu.Unlock()
+
+		if r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("expected Accept text/event-stream, got %q", r.Header.Get("Accept"))
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		switch conn {
+		case 1:
+			// the connection drops partway through the third event
+			w.Write([]byte("retry: 10\n: comment\nid: 1\ndata: one\n\nid: 2\nevent: update\ndata: two\ndata: lines\n\nid: 3\ndata: partial"))
+		case 2:
+			w.Write([]byte("id: 3\r\ndata: three\r\n\r\n"))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []Event
+	start := time.Now()
+
+	err = client.SubscribeEvents(context.Background(), "/events", nil, func(event Event) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the retry directive to shorten the reconnect delay, took %s", elapsed)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %+v", events)
+	}
+
+	if events[0].Event != "message" || events[0].Data != "one" {
+		t.Fatalf("unexpected first event %+v", events[0])
+	}
+
+	if events[1].Event != "update" || events[1].Data != "two\nlines" {
+		t.Fatalf("unexpected second event %+v", events[1])
+	}
+
+	if events[2].ID != "3" || events[2].Data != "three" {
+		t.Fatalf("unexpected third event %+v", events[2])
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(lastEventIds) != 3 || lastEventIds[0] != "" || lastEventIds[1] != "2" || lastEventIds[2] != "3" {
+		t.Fatalf("expected reconnects to resend the last event id, got %q", lastEventIds)
+	}
+}
+
+func TestSubscribeEventsHandlerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("id: 1\ndata: one\n\nid: 2\ndata: two\n\n"))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errStop := errors.New("stop")
+
+	calls := 0
+	err = client.SubscribeEvents(context.Background(), "/events", nil, func(event Event) error {
+		calls++
+		return errStop
+	})
+
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Fatalf("expected the handler error to stop the subscription after 1 call, got %v after %d", err, calls)
+	}
+}
+
+func TestSubscribeEventsContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	baseUrl := srv.URL
+	srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: baseUrl})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = client.SubscribeEvents(ctx, "/events", nil, func(Event) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected reconnects to stop at the context deadline, got %v", err)
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		switch r.URL.Path {
+		case "/done":
+			w.Write([]byte(": comment\nid: 1\ndata: hello\ndata: world\n\nevent: tick\ndata: 2\n\ndata: [DONE]\n\ndata: after\n\n"))
+		case "/eof":
+			w.Write([]byte("data: a\n\n"))
+		case "/hang":
+			w.Write([]byte("data: a\n\n"))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("done sentinel", func(t *testing.T) {
+		events, errs, err := client.StreamEvents(context.Background(), "/done", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []Event
+		for event := range events {
+			got = append(got, event)
+		}
+
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+
+		want := []Event{
+			{ID: "1", Event: "message", Data: "hello\nworld"},
+			{ID: "1", Event: "tick", Data: "2"},
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("expected %d events before [DONE], got %+v", len(want), got)
+		}
+
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("event %d: expected %+v, got %+v", i, want[i], got[i])
+			}
+		}
+	})
+
+	t.Run("end of stream", func(t *testing.T) {
+		events, errs, err := client.StreamEvents(context.Background(), "/eof", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var count int
+		for range events {
+			count++
+		}
+
+		if err := <-errs; err != nil {
+			t.Fatalf("expected a clean end of stream, got %v", err)
+		}
+
+		if count != 1 {
+			t.Fatalf("expected 1 event, got %d", count)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events, errs, err := client.StreamEvents(ctx, "/hang", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		<-events
+		cancel()
+
+		for range events {
+		}
+
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package httpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// timeoutKey holds a per request timeout override in the request context
+type timeoutKey struct{}
+
+// streamContext lifts the client timeout for long lived streams, so the context alone bounds them
+func streamContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, time.Duration(0))
+}
+
+// sleepCtx waits for the supplied delay, returning the context error if it is done first
+func sleepCtx(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// GetWithTimeout makes a GET request like Get with the supplied timeout in place of the client timeout, which may be shorter or longer.
+// A custom client's own http.Client.Timeout still applies as a hard ceiling
+func (c *Client) GetWithTimeout(ctx context.Context, resource string, timeout time.Duration, headers map[string]string, decoded interface{}) (*http.Response, error) {
+	return c.do(context.WithValue(ctx, timeoutKey{}, timeout), http.MethodGet, resource, nil, headers, decoded)
+}
+
+// roundTrip sends the request with the client timeout, or the per request override, enforced through the request context so it can
+// be extended per request. The timeout covers reading the body and is released once the body is closed
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	timeout := c.timeout
+	if override, ok := req.Context().Value(timeoutKey{}).(time.Duration); ok {
+		timeout = override
+	}
+
+	if timeout <= 0 {
+		return c.doHooked(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+
+	resp, err := c.doHooked(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = &cancelBody{resp.Body, cancel}
+
+	return resp, nil
+}
+
+// cancelBody releases the request context once the response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
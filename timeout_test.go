@@ -0,0 +1,43 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetWithTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), &Config{BaseUrl: srv.URL, Timeout: int(100 * time.Millisecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the default timeout to expire, got %v", err)
+	}
+
+	if _, err := client.GetWithTimeout(context.Background(), "/", time.Second, nil, nil); err != nil {
+		t.Fatalf("expected a longer override to extend past the default timeout, got %v", err)
+	}
+
+	start := time.Now()
+
+	if _, err := client.GetWithTimeout(context.Background(), "/", 20*time.Millisecond, nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a shorter override to expire, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 90*time.Millisecond {
+		t.Fatalf("expected a shorter override to expire before the default timeout, took %s", elapsed)
+	}
+}